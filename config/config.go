@@ -1,6 +1,12 @@
 package config
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	_ "time/tzdata" // タイムゾーンデータのないコンテナでも TIME_ZONE を読み込めるようにする
+
 	"github.com/caarlos0/env/v6"
 	"github.com/joho/godotenv"
 )
@@ -8,17 +14,170 @@ import (
 type Config struct {
 	Env         string `env:"TODO_ENV" envDefault:"dev"`
 	Port        string `env:"PORT" envDefault:":8080"`
-	DSN         string `env:"DSN" envDefault:"user:password@tcp(localhost:3306)/dbname"`
+	DSN         string `env:"DSN" envDefault:"user:password@tcp(localhost:3306)/dbname"` // postgres:// は PostgreSQL、mysql:// またはスキームなしは MySQL
 	ExternalAPI string `env:"EXTERNAL_API" envDefault:"https://geoapi.heartrails.com/api/json?method=searchByPostal&postal="`
+
+	// DB の接続プールの設定（0 の場合は制限しない）
+	DBMaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" envDefault:"25"`
+	DBMaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" envDefault:"10"`
+	DBConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"5m"`
+
+	// 起動時の DB への接続の試行回数と、再試行までの待ち時間（倍々に増やし、最大値で頭打ちにする）
+	DBConnectAttempts   int           `env:"DB_CONNECT_ATTEMPTS" envDefault:"5"`
+	DBConnectBackoff    time.Duration `env:"DB_CONNECT_BACKOFF" envDefault:"1s"`
+	DBConnectMaxBackoff time.Duration `env:"DB_CONNECT_MAX_BACKOFF" envDefault:"30s"`
+
+	// 起動後に DB の疎通を確認する間隔（結果を /readyz に反映する）
+	DBPingInterval time.Duration `env:"DB_PING_INTERVAL" envDefault:"10s"`
+
+	// 距離行列 API の設定
+	MatrixConcurrency int           `env:"MATRIX_CONCURRENCY" envDefault:"4"`
+	MatrixMaxCells    int           `env:"MATRIX_MAX_CELLS" envDefault:"2500"`
+	GeoCacheTTL       time.Duration `env:"GEO_CACHE_TTL" envDefault:"10m"`
+
+	// ローカルの郵便番号データセット（ヘッダー付き CSV）
+	PostalDataset        string  `env:"POSTAL_DATASET"`
+	PostalDatasetVersion string  `env:"POSTAL_DATASET_VERSION"` // YYYY-MM（未指定の場合はファイルの更新年月）
+	PostalDiffDir        string  `env:"POSTAL_DIFF_DIR"`        // 月次の ADD_YYMM.CSV / DEL_YYMM.CSV を置くディレクトリ
+	NearbyMaxRadiusKm    float64 `env:"NEARBY_MAX_RADIUS_KM" envDefault:"50"`
+
+	// アクセスログの保存先（db・sqlite・memory）。db は DSN の DB（MySQL または PostgreSQL）
+	// 郵便番号データセットは保存先によらず DSN の DB に保存する
+	AccessLogStore      string `env:"ACCESS_LOG_STORE" envDefault:"db"`
+	AccessLogSQLitePath string `env:"ACCESS_LOG_SQLITE_PATH" envDefault:"access_logs.db"`
+
+	// アクセスログの非同期書き込み（ACCESS_LOG_OVERFLOW は block または drop）
+	AccessLogAsync         bool          `env:"ACCESS_LOG_ASYNC" envDefault:"true"`
+	AccessLogBufferSize    int           `env:"ACCESS_LOG_BUFFER_SIZE" envDefault:"10000"`
+	AccessLogBatchSize     int           `env:"ACCESS_LOG_BATCH_SIZE" envDefault:"200"`
+	AccessLogFlushInterval time.Duration `env:"ACCESS_LOG_FLUSH_INTERVAL" envDefault:"1s"`
+	AccessLogOverflow      string        `env:"ACCESS_LOG_OVERFLOW" envDefault:"drop"`
+	AccessLogCloseTimeout  time.Duration `env:"ACCESS_LOG_CLOSE_TIMEOUT" envDefault:"10s"` // シャットダウン時に書き出しを待つ時間
+
+	// DB に接続できない間のアクセスログの退避先（未指定の場合は退避しない）と再送の間隔
+	AccessLogSpoolPath      string        `env:"ACCESS_LOG_SPOOL_PATH"`
+	AccessLogReplayInterval time.Duration `env:"ACCESS_LOG_REPLAY_INTERVAL" envDefault:"30s"`
+
+	// 生のアクセスログの保持日数（0 の場合は削除しない）。期限を過ぎた行は日次に集約してから削除する
+	AccessLogRetentionDays  int           `env:"ACCESS_LOG_RETENTION_DAYS" envDefault:"0"`
+	AccessLogPurgeBatchSize int           `env:"ACCESS_LOG_PURGE_BATCH_SIZE" envDefault:"1000"`
+	AccessLogPurgeInterval  time.Duration `env:"ACCESS_LOG_PURGE_INTERVAL" envDefault:"1h"`
+
+	// 住所検索のライブストリームの購読者ごとのバッファ（溢れた購読者は切断する）と、無通信時のハートビートの間隔
+	LookupStreamBufferSize int           `env:"LOOKUP_STREAM_BUFFER_SIZE" envDefault:"64"`
+	LookupStreamHeartbeat  time.Duration `env:"LOOKUP_STREAM_HEARTBEAT" envDefault:"15s"`
+
+	// 直近にアクセスの多い郵便番号の集計（区間の長さ、集計できる最大の期間、区間ごとに数える郵便番号の数）
+	TrendingResolution time.Duration `env:"TRENDING_RESOLUTION" envDefault:"1m"`
+	TrendingMaxWindow  time.Duration `env:"TRENDING_MAX_WINDOW" envDefault:"1h"`
+	TrendingCapacity   int           `env:"TRENDING_CAPACITY" envDefault:"1000"`
+
+	// クライアントの識別子（API キーまたは IP アドレスのハッシュ）のソルト
+	// 未指定の場合は起動ごとにランダムに生成するため、再起動をまたぐとクライアントの種類数が多めになる
+	ClientIDSalt string `env:"CLIENT_ID_SALT"`
+
+	// アクセスログの日付の解釈と時系列の集計に使うタイムゾーン
+	TimeZone *time.Location `env:"TIME_ZONE" envDefault:"Asia/Tokyo"`
 }
 
+// アクセスログのバッチの最大件数
+// 1 行 13 列のプレースホルダーが MySQL・PostgreSQL の上限（65535 個）を超えないようにする
+const MaxAccessLogBatchSize = 5000
+
+// 古いアクセスログを一度に集約・削除する最大件数
+// 削除する行の ID をプレースホルダーで渡すため、MySQL・PostgreSQL の上限（65535 個）を超えないようにする
+const MaxAccessLogPurgeBatchSize = 10000
+
 func New() (*Config, error) {
 	// .env ファイルを読み込む
 	_ = godotenv.Load()
 
 	cfg := &Config{}
-	if err := env.Parse(cfg); err != nil {
+	if err := env.ParseWithFuncs(cfg, parsers); err != nil {
 		return nil, err
 	}
+	if _, ok := dsnDrivers[cfg.dsnScheme()]; !ok {
+		return nil, fmt.Errorf("DSN scheme must be mysql, postgres or postgresql, got %q", cfg.dsnScheme())
+	}
+	if !hasWholeHourOffsets(cfg.TimeZone) {
+		return nil, fmt.Errorf("TIME_ZONE must have a whole-hour UTC offset, got %s", cfg.TimeZone)
+	}
+	if cfg.DBConnectAttempts < 1 {
+		return nil, fmt.Errorf("DB_CONNECT_ATTEMPTS must be at least 1, got %d", cfg.DBConnectAttempts)
+	}
+	if cfg.DBPingInterval <= 0 {
+		return nil, fmt.Errorf("DB_PING_INTERVAL must be positive, got %s", cfg.DBPingInterval)
+	}
+	if cfg.AccessLogStore != "db" && cfg.AccessLogStore != "sqlite" && cfg.AccessLogStore != "memory" {
+		return nil, fmt.Errorf("ACCESS_LOG_STORE must be db, sqlite or memory, got %q", cfg.AccessLogStore)
+	}
+	if cfg.AccessLogOverflow != "block" && cfg.AccessLogOverflow != "drop" {
+		return nil, fmt.Errorf("ACCESS_LOG_OVERFLOW must be block or drop, got %q", cfg.AccessLogOverflow)
+	}
+	if cfg.AccessLogBatchSize < 1 || cfg.AccessLogBatchSize > MaxAccessLogBatchSize {
+		return nil, fmt.Errorf("ACCESS_LOG_BATCH_SIZE must be between 1 and %d, got %d", MaxAccessLogBatchSize, cfg.AccessLogBatchSize)
+	}
+	if cfg.AccessLogSpoolPath != "" && cfg.AccessLogReplayInterval <= 0 {
+		return nil, fmt.Errorf("ACCESS_LOG_REPLAY_INTERVAL must be positive, got %s", cfg.AccessLogReplayInterval)
+	}
+	if cfg.AccessLogPurgeBatchSize < 1 || cfg.AccessLogPurgeBatchSize > MaxAccessLogPurgeBatchSize {
+		return nil, fmt.Errorf("ACCESS_LOG_PURGE_BATCH_SIZE must be between 1 and %d, got %d", MaxAccessLogPurgeBatchSize, cfg.AccessLogPurgeBatchSize)
+	}
+	if cfg.AccessLogPurgeInterval <= 0 {
+		return nil, fmt.Errorf("ACCESS_LOG_PURGE_INTERVAL must be positive, got %s", cfg.AccessLogPurgeInterval)
+	}
 	return cfg, nil
 }
+
+// タイムゾーンの UTC からの時差が 1 時間単位か（夏時間も確認する）
+// 時系列は UTC の 1 時間ごとの集計を振り分けるため、30 分単位などの時差では正しく集計できない
+func hasWholeHourOffsets(loc *time.Location) bool {
+	year := time.Now().Year()
+	for _, month := range []time.Month{time.January, time.July} {
+		if _, offset := time.Date(year, month, 1, 0, 0, 0, 0, loc).Zone(); offset%3600 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// DSN のスキームと DB の種類（database/sql のドライバー名）
+var dsnDrivers = map[string]string{
+	"":           "mysql",
+	"mysql":      "mysql",
+	"postgres":   "postgres",
+	"postgresql": "postgres",
+}
+
+// DSN のスキーム（スキームがない場合は空）
+func (c *Config) dsnScheme() string {
+	if i := strings.Index(c.DSN, "://"); i >= 0 {
+		return strings.ToLower(c.DSN[:i])
+	}
+	return ""
+}
+
+// DSN のスキームから DB の種類（mysql または postgres）を返す
+func (c *Config) DBDriver() string {
+	return dsnDrivers[c.dsnScheme()]
+}
+
+// ドライバーに渡す DSN を返す（MySQL のドライバーは mysql:// のスキームを受け付けないため取り除く）
+func (c *Config) DriverDSN() string {
+	if c.dsnScheme() == "mysql" {
+		return c.DSN[len("mysql://"):]
+	}
+	return c.DSN
+}
+
+// 標準では対応していない型の変換
+var parsers = map[reflect.Type]env.ParserFunc{
+	// *time.Location のフィールドには要素の値を設定する
+	reflect.TypeOf(time.Location{}): func(v string) (interface{}, error) {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			return nil, err
+		}
+		return *loc, nil
+	},
+}
@@ -1,6 +1,166 @@
 package entity
 
+import (
+	"encoding/xml"
+	"time"
+)
+
+// 住所検索の失敗の分類
+const (
+	ErrorClassNone     = ""
+	ErrorClassNotFound = "not_found"      // 該当する住所がない
+	ErrorClassUpstream = "upstream_error" // 外部 API の呼び出しに失敗
+)
+
+// アクセスログに保存する郵便番号の最大長（access_logs.postal_code の長さ）
+const AccessLogPostalCodeMaxLength = 8
+
+// アクセスログの集計結果の並び順
+const (
+	AccessLogSortCount      = "count"
+	AccessLogSortPostalCode = "postal_code"
+	AccessLogSortLastSeen   = "last_seen"
+)
+
+// 昇順・降順
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
 type AccessLog struct {
-	PostalCode   string `json:"postal_code"`
-	RequestCount int    `json:"request_count"`
+	PostalCode    string    `json:"postal_code" xml:"postal_code"`
+	RequestCount  int       `json:"request_count" xml:"request_count"`
+	FirstSeen     time.Time `json:"first_seen" xml:"first_seen"`
+	LastSeen      time.Time `json:"last_seen" xml:"last_seen"`
+	UniqueClients int       `json:"unique_clients" xml:"unique_clients"` // クライアントの種類数の推定値
+}
+
+// AccessLogList はアクセスログの集計結果のレスポンス
+type AccessLogList struct {
+	XMLName    xml.Name    `json:"-" xml:"access_logs"`
+	AccessLogs []AccessLog `json:"access_logs" xml:"access_log"`
+	NextCursor string      `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"` // 次のページがある場合のみ
+	// 日次に集約した日の途中を指定したため、日の境界に広げて集計した期間（広げた場合のみ）
+	AdjustedFrom *time.Time `json:"adjusted_from,omitempty" xml:"adjusted_from,omitempty"`
+	AdjustedTo   *time.Time `json:"adjusted_to,omitempty" xml:"adjusted_to,omitempty"`
+}
+
+// AccessLogQuery はアクセスログの集計条件
+type AccessLogQuery struct {
+	From   time.Time // この日時以降（ゼロ値の場合は指定なし）
+	To     time.Time // この日時より前（ゼロ値の場合は指定なし）
+	Prefix string    // 郵便番号の前方一致
+	Sort   string
+	Order  string
+	Limit  int              // 0 の場合は件数の制限なし
+	After  *AccessLogCursor // 前のページの最後の行（キーセットページング）
+}
+
+// AccessLogCursor は集計結果のページングの位置
+type AccessLogCursor struct {
+	Sort         string    `json:"s"`
+	Order        string    `json:"o"`
+	RequestCount int       `json:"c,omitempty"`
+	LastSeen     time.Time `json:"l"`
+	PostalCode   string    `json:"p"`
+}
+
+// AccessLogEntry は 1 回の住所検索のアクセスログ
+type AccessLogEntry struct {
+	ID             string        `json:"id"` // 再送時の重複を防ぐための一意な ID
+	PostalCode     string        `json:"postal_code"`
+	Status         int           `json:"status"` // HTTP ステータスコード
+	Latency        time.Duration `json:"latency_ns"`
+	ClientIP       string        `json:"client_ip"`
+	ClientID       string        `json:"client_id,omitempty"` // API キーまたは IP アドレスのソルト付きハッシュ
+	UserAgent      string        `json:"user_agent"`
+	RequestID      string        `json:"request_id"`
+	HitCount       int           `json:"hit_count"`
+	ErrorClass     string        `json:"error_class"`
+	PrefectureCode string        `json:"prefecture_code,omitempty"` // ローカルデータセットから解決した地域（解決できない場合は空）
+	CityCode       string        `json:"city_code,omitempty"`       // 全国地方公共団体コード（5 桁）
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// AccessLogBucket は時系列の集計単位ごとのアクセス数
+type AccessLogBucket struct {
+	Start time.Time `json:"start" xml:"start"`
+	Count int       `json:"count" xml:"count"`
+}
+
+// AccessLogTimeseries はアクセス数の時系列
+type AccessLogTimeseries struct {
+	XMLName    xml.Name          `json:"-" xml:"access_log_timeseries"`
+	PostalCode string            `json:"postal_code,omitempty" xml:"postal_code,omitempty"`
+	Interval   string            `json:"interval" xml:"interval"`
+	TimeZone   string            `json:"time_zone" xml:"time_zone"`
+	From       time.Time         `json:"from" xml:"from"`
+	To         time.Time         `json:"to" xml:"to"`
+	Buckets    []AccessLogBucket `json:"buckets" xml:"bucket"`
+}
+
+// AccessLogCounterMismatch はカウンターと生のアクセスログから計算した件数の食い違い
+type AccessLogCounterMismatch struct {
+	PostalCode string `json:"postal_code"`
+	Counted    int    `json:"counted"` // カウンターの件数（カウンターがない場合は 0）
+	Actual     int    `json:"actual"`  // アクセスログから計算した件数（アクセスログがない場合は 0）
+}
+
+// 地域ごとの集計の単位
+const (
+	RegionLevelPrefecture = "prefecture"
+	RegionLevelCity       = "city"
+)
+
+// AccessLogRegionCount は地域コードごとのアクセス数
+type AccessLogRegionCount struct {
+	Code         string // 地域を解決できなかったアクセスは空
+	RequestCount int
+}
+
+// AccessLogRegion は地域ごとのアクセス数と全体に占める割合
+type AccessLogRegion struct {
+	Code           string  `json:"code" xml:"code"`
+	Name           string  `json:"name" xml:"name"`
+	PrefectureCode string  `json:"prefecture_code,omitempty" xml:"prefecture_code,omitempty"` // 市区町村の場合のみ
+	Prefecture     string  `json:"prefecture,omitempty" xml:"prefecture,omitempty"`
+	RequestCount   int     `json:"request_count" xml:"request_count"`
+	Share          float64 `json:"share" xml:"share"` // 全体に占める割合 [%]
+}
+
+// AccessLogRegionList は地域ごとのアクセス数の一覧
+type AccessLogRegionList struct {
+	XMLName    xml.Name          `json:"-" xml:"access_log_regions"`
+	Level      string            `json:"level" xml:"level"`
+	Total      int               `json:"total" xml:"total"`           // 期間内のアクセス数
+	Unresolved int               `json:"unresolved" xml:"unresolved"` // 地域を解決できなかったアクセス数
+	Regions    []AccessLogRegion `json:"regions" xml:"region"`
+}
+
+// LookupEvent はライブストリームに流す住所検索の結果
+type LookupEvent struct {
+	PostalCode string    `json:"postal_code"`
+	Status     int       `json:"status"`
+	LatencyMs  float64   `json:"latency_ms"`
+	Address    string    `json:"address,omitempty"` // 見つからなかった場合は空
+	RequestID  string    `json:"request_id,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// TrendingPostalCode は直近の期間にアクセスの多い郵便番号
+type TrendingPostalCode struct {
+	PostalCode    string   `json:"postal_code" xml:"postal_code"`
+	RequestCount  int      `json:"request_count" xml:"request_count"`
+	PreviousCount int      `json:"previous_count" xml:"previous_count"` // 直前の同じ長さの期間のアクセス数
+	Growth        *float64 `json:"growth" xml:"growth,omitempty"`       // 直前の期間からの増加率 [%]（直前が 0 件の場合は null）
+}
+
+// TrendingList は直近の期間にアクセスの多い郵便番号の一覧
+type TrendingList struct {
+	XMLName     xml.Name             `json:"-" xml:"trending"`
+	Window      string               `json:"window" xml:"window"`
+	From        time.Time            `json:"from" xml:"from"`
+	To          time.Time            `json:"to" xml:"to"`
+	PostalCodes []TrendingPostalCode `json:"postal_codes" xml:"postal_code"`
 }
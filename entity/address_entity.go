@@ -1,10 +1,17 @@
 package entity
 
+import "encoding/xml"
+
 type Address struct {
-	PostalCode       string  `json:"postal_code"`
-	HitCount         int     `json:"hit_count"`
-	CommonAddress    string  `json:"address"`
-	TokyoStaDistance float64 `json:"tokyo_sta_distance"`
+	XMLName          xml.Name `json:"-" xml:"address"`
+	PostalCode       string   `json:"postal_code" xml:"postal_code"`
+	HitCount         int      `json:"hit_count" xml:"hit_count"`
+	CommonAddress    string   `json:"address" xml:"address"`
+	TokyoStaDistance float64  `json:"tokyo_sta_distance" xml:"tokyo_sta_distance"`
+	PrefectureCode   string   `json:"prefecture_code,omitempty" xml:"prefecture_code,omitempty"`
+	MunicipalityCode string   `json:"municipality_code,omitempty" xml:"municipality_code,omitempty"` // 検査数字付き 6 桁
+	SupersededBy     string   `json:"superseded_by,omitempty" xml:"superseded_by,omitempty"`         // 廃止された郵便番号の後継
+	Deprecation      string   `json:"deprecation,omitempty" xml:"deprecation,omitempty"`
 }
 
 func NewAddress(postalCode string, hitCount int, commonAddress string, tokyoStaDistance float64) *Address {
@@ -0,0 +1,32 @@
+package entity
+
+import "encoding/xml"
+
+// DistanceMatrixRequest は距離行列 API のリクエストボディ
+type DistanceMatrixRequest struct {
+	Origins      []string `json:"origins"`
+	Destinations []string `json:"destinations"`
+}
+
+// DistanceCell は距離行列の 1 セル（出発地 × 目的地）
+type DistanceCell struct {
+	Origin      string   `json:"origin" xml:"origin"`
+	Destination string   `json:"destination" xml:"destination"`
+	DistanceKm  *float64 `json:"distance_km,omitempty" xml:"distance_km,omitempty"`
+	Error       string   `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// DistanceMatrix は出発地と目的地の全組み合わせの距離
+// XML では行を分けずにセルを出発地・目的地の順に並べる
+type DistanceMatrix struct {
+	XMLName      xml.Name         `json:"-" xml:"distance_matrix"`
+	Origins      []string         `json:"origins" xml:"origins>origin"`
+	Destinations []string         `json:"destinations" xml:"destinations>destination"`
+	Rows         [][]DistanceCell `json:"rows" xml:"cells>cell"`
+}
+
+// GeoPoint は緯度経度の組
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
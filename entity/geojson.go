@@ -0,0 +1,37 @@
+package entity
+
+// GeoJSON (RFC 7946) の型
+const (
+	GeoJSONFeatureCollection = "FeatureCollection"
+	GeoJSONFeature           = "Feature"
+	GeoJSONPoint             = "Point"
+)
+
+// FeatureCollection は GeoJSON の FeatureCollection
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	BBox     []float64 `json:"bbox,omitempty"`
+	Features []Feature `json:"features"`
+}
+
+// Feature は GeoJSON の Feature
+type Feature struct {
+	Type       string                 `json:"type"`
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry は GeoJSON の Geometry（座標は [経度, 緯度] の順）
+type Geometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// 緯度経度から Point の Feature を作成
+func NewPointFeature(lat, lon float64, properties map[string]interface{}) Feature {
+	return Feature{
+		Type:       GeoJSONFeature,
+		Geometry:   Geometry{Type: GeoJSONPoint, Coordinates: []float64{lon, lat}},
+		Properties: properties,
+	}
+}
@@ -0,0 +1,77 @@
+package entity
+
+import "time"
+
+// データセットの取り込み種別
+const (
+	DatasetKindFull = "full" // 全件データ
+	DatasetKindDiff = "diff" // 月次の追加・削除データ
+)
+
+// 郵便番号の変更種別
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// データセットのバージョンに記録する取り込み元の最大長（postal_dataset_versions.source の文字数）
+const DatasetSourceMaxLength = 255
+
+// DatasetVersion は取り込み済みの郵便番号データセットのバージョン
+type DatasetVersion struct {
+	ID          int       `json:"id"`
+	Version     string    `json:"version"` // YYYY-MM
+	Kind        string    `json:"kind"`
+	Source      string    `json:"source"`
+	Checksum    string    `json:"checksum"`
+	RecordCount int       `json:"record_count"`
+	ImportedAt  time.Time `json:"imported_at"`
+}
+
+// PostalCodeChange はバージョン間での郵便番号の変更
+type PostalCodeChange struct {
+	Version      string   `json:"version"`
+	PostalCode   string   `json:"postal_code"`
+	ChangeType   string   `json:"change_type"`
+	OldAddresses []string `json:"old_addresses"`
+	NewAddresses []string `json:"new_addresses"`
+}
+
+// PostalCodeChanges は変更種別ごとにまとめた変更履歴
+type PostalCodeChanges struct {
+	Since         string             `json:"since"`
+	LatestVersion string             `json:"latest_version"`
+	Added         []PostalCodeChange `json:"added"`
+	Removed       []PostalCodeChange `json:"removed"`
+	Modified      []PostalCodeChange `json:"modified"`
+}
+
+// PostalCodeSuccession は廃止された郵便番号と後継の郵便番号の対応
+type PostalCodeSuccession struct {
+	Version             string `json:"version"`
+	RetiredPostalCode   string `json:"retired_postal_code"`
+	SuccessorPostalCode string `json:"successor_postal_code"`
+}
+
+// DatasetUpdate は 1 バージョン分のデータセットの更新内容
+type DatasetUpdate struct {
+	Version     DatasetVersion
+	Records     map[string][]PostalRecord // 郵便番号ごとの新しいデータ（空の場合は削除）
+	ReplaceAll  bool                      // true の場合は既存のデータをすべて削除してから Records を登録する
+	Changes     []PostalCodeChange
+	Successions []PostalCodeSuccession
+}
+
+type PostalDatasetRepository interface {
+	// 取り込み済みのバージョンを古い順に返す
+	ListVersions() ([]DatasetVersion, error)
+	// 現在の郵便番号データを返す（postalCodes が nil の場合は全件）
+	LoadRecords(postalCodes []string) ([]PostalRecord, error)
+	// バージョンの記録・データの置き換え・変更履歴と後継の保存を 1 トランザクションで行う
+	SaveVersion(update DatasetUpdate) error
+	// 指定したバージョン以降の変更履歴を返す
+	ChangesSince(version string) ([]PostalCodeChange, error)
+	// 廃止された郵便番号と後継の対応をすべて返す
+	ListSuccessions() ([]PostalCodeSuccession, error)
+}
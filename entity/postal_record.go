@@ -0,0 +1,53 @@
+package entity
+
+// PostalRecord はローカルの郵便番号データセットの 1 行
+type PostalRecord struct {
+	PostalCode     string  `json:"postal_code"`
+	JISCode        string  `json:"jis_code"` // 全国地方公共団体コード（5 桁）
+	Prefecture     string  `json:"prefecture"`
+	PrefectureKana string  `json:"prefecture_kana"`
+	City           string  `json:"city"`
+	CityKana       string  `json:"city_kana"`
+	Town           string  `json:"town"`
+	TownKana       string  `json:"town_kana"`
+	Lat            float64 `json:"lat"`
+	Lon            float64 `json:"lon"`
+}
+
+// 都道府県コード（全国地方公共団体コードの上 2 桁）を返す
+func (r PostalRecord) PrefectureCode() string {
+	if len(r.JISCode) < 2 {
+		return ""
+	}
+	return r.JISCode[:2]
+}
+
+// 外部 API と同じ AddressLocation 形式に変換
+func (r PostalRecord) Location() AddressLocation {
+	return AddressLocation{
+		Prefecture: r.Prefecture,
+		City:       r.City,
+		Town:       r.Town,
+		Lat:        r.Lat,
+		Lon:        r.Lon,
+	}
+}
+
+// NearbyAddress は半径検索でヒットした郵便番号
+type NearbyAddress struct {
+	PostalCode    string  `json:"postal_code"`
+	CommonAddress string  `json:"address"`
+	Lat           float64 `json:"lat"`
+	Lon           float64 `json:"lon"`
+	DistanceKm    float64 `json:"distance_km"`
+}
+
+// NearbyResult は半径検索の結果（ページング済み）
+type NearbyResult struct {
+	Center   GeoPoint        `json:"center"`
+	RadiusKm float64         `json:"radius_km"`
+	Total    int             `json:"total"`
+	Limit    int             `json:"limit"`
+	Offset   int             `json:"offset"`
+	Results  []NearbyAddress `json:"results"`
+}
@@ -0,0 +1,35 @@
+package entity
+
+// Prefecture は都道府県
+type Prefecture struct {
+	Code            string `json:"code"`
+	Name            string `json:"name"`
+	Kana            string `json:"kana"`
+	PostalCodeCount int    `json:"postal_code_count"`
+}
+
+// City は市区町村
+type City struct {
+	Code           string   `json:"code"`
+	PrefectureCode string   `json:"prefecture_code"`
+	Name           string   `json:"name"`
+	Kana           string   `json:"kana"`
+	PostalCodes    []string `json:"postal_codes"`
+}
+
+// Town は町域
+type Town struct {
+	Name       string `json:"name"`
+	Kana       string `json:"kana"`
+	PostalCode string `json:"postal_code"`
+}
+
+// Municipality は全国地方公共団体コードに対応する市区町村
+type Municipality struct {
+	Code           string   `json:"code"` // 検査数字付き 6 桁
+	PrefectureCode string   `json:"prefecture_code"`
+	Prefecture     string   `json:"prefecture"`
+	Name           string   `json:"name"`
+	Kana           string   `json:"kana"`
+	PostalCodes    []string `json:"postal_codes"`
+}
@@ -1,9 +1,41 @@
 package entity
 
+import (
+	"context"
+	"time"
+)
+
 type AddressRepository interface {
 	FetchAddressData(postalCode string) ([]AddressLocation, error)
 }
 
+// AccessLogRepository はアクセスログの保存先（MySQL・SQLite・メモリ）
+// 日時はすべて UTC として扱い、from・to がゼロ値の場合は期間で絞り込まない
+type AccessLogRepository interface {
+	InsertAccessLog(entry AccessLogEntry) error
+	// 複数のアクセスログを保存し、カウンターとクライアントのスケッチに反映する（同じ ID は重複して保存しない）
+	InsertAccessLogs(entries []AccessLogEntry) error
+	// 条件に一致するアクセスログを郵便番号ごとに集計して返す（UniqueClients は含まない）
+	GetAccessLogs(q AccessLogQuery) ([]AccessLog, error)
+	// 期間内のアクセス数を 1 時間ごとに集計して返す（postalCode が空の場合はすべての郵便番号）
+	CountAccessLogsByHour(postalCode string, from, to time.Time) ([]AccessLogBucket, error)
+	// 期間内のアクセス数を地域コードごとに集計して返す（地域を解決できなかったアクセスはコードが空）
+	CountAccessLogsByRegion(level string, from, to time.Time) ([]AccessLogRegionCount, error)
+	// 郵便番号ごとに、期間と重なる日のクライアントの種類数を推定して返す
+	CountUniqueClients(postalCodes []string, from, to time.Time) (map[string]int, error)
+	// 期間内の生のアクセスログを保存順に 1 件ずつ fn に渡す
+	StreamAccessLogs(ctx context.Context, from, to time.Time, fn func(AccessLogEntry) error) error
+	// before より前のアクセスログを最大 batchSize 件、日次の集約に加えてから削除し、削除した件数を返す
+	RollupAndPurge(before time.Time, batchSize int) (int, error)
+	// 郵便番号ごとのカウンター、および生のアクセスログと日次の集約から計算した件数を郵便番号順に返す
+	ListCounters() ([]AccessLog, error)
+	ComputeCounters() ([]AccessLog, error)
+	// カウンターを作り直す。InitializeCounters はカウンターが空でアクセスログがある場合のみ作り直す
+	RebuildCounters() error
+	InitializeCounters() error
+	Ping() error
+}
+
 type AddressLocation struct {
 	Prefecture string  `json:"prefecture"`
 	City       string  `json:"city"`
@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/i18n"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/dkpcb/finatext_kadai_2/util"
+	"github.com/labstack/echo/v4"
+)
+
+// アクセスログの集計結果のページングの既定値と上限
+const (
+	defaultAccessLogLimit = 100
+	maxAccessLogLimit     = 1000
+	defaultTrendingLimit  = 10
+	maxTrendingLimit      = 100
+)
+
+// アクセスログの集計条件のクエリパラメータを検証して変換（不正な場合はメッセージのキーを返す）
+func parseAccessLogQuery(c echo.Context, loc *time.Location) (entity.AccessLogQuery, i18n.MessageKey) {
+	q := entity.AccessLogQuery{
+		Prefix: c.QueryParam("prefix"),
+		Sort:   c.QueryParam("sort"),
+		Order:  c.QueryParam("order"),
+	}
+
+	var ok bool
+	if q.From, ok = parseTimeParam(c.QueryParam("from"), false, loc); !ok {
+		return q, i18n.MsgFromInvalid
+	}
+	if q.To, ok = parseTimeParam(c.QueryParam("to"), true, loc); !ok {
+		return q, i18n.MsgToInvalid
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return q, i18n.MsgTimeRangeInvalid
+	}
+
+	if q.Prefix != "" && (len(q.Prefix) > 7 || !isDigits(q.Prefix)) {
+		return q, i18n.MsgPrefixInvalid
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = entity.AccessLogSortCount
+	case entity.AccessLogSortCount, entity.AccessLogSortPostalCode, entity.AccessLogSortLastSeen:
+	default:
+		return q, i18n.MsgSortInvalid
+	}
+	switch q.Order {
+	case "":
+		// 件数と最終アクセス日時は多い順・新しい順、郵便番号は昇順
+		q.Order = entity.OrderDesc
+		if q.Sort == entity.AccessLogSortPostalCode {
+			q.Order = entity.OrderAsc
+		}
+	case entity.OrderAsc, entity.OrderDesc:
+	default:
+		return q, i18n.MsgOrderInvalid
+	}
+
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		after, err := decodeAccessLogCursor(cursor)
+		if err != nil || after.Sort != q.Sort || after.Order != q.Order {
+			return q, i18n.MsgCursorInvalid
+		}
+		q.After = after
+	}
+	return q, ""
+}
+
+// 日時のクエリパラメータを変換（RFC 3339 の日時、または loc での YYYY-MM-DD の日付）
+// 日付で指定した場合、end が true なら翌日の 0 時（その日を含む）とする
+func parseTimeParam(v string, end bool, loc *time.Location) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+// 時系列の期間を省略した場合の長さ
+var defaultTimeseriesSpans = map[string]time.Duration{
+	util.IntervalHour: 24 * time.Hour,
+	util.IntervalDay:  30 * 24 * time.Hour,
+	util.IntervalWeek: 12 * 7 * 24 * time.Hour,
+}
+
+// HandleAccessLogTimeseries はアクセス数を集計単位ごとの時系列で返す
+func (h *Handler) HandleAccessLogTimeseries(c echo.Context) error {
+	// レスポンス形式を決定（対応していない場合は406エラーを返す）
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return notAcceptable(c)
+	}
+
+	interval := c.QueryParam("interval")
+	if interval == "" {
+		interval = util.IntervalDay
+	}
+	span, ok := defaultTimeseriesSpans[interval]
+	if !ok {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgIntervalInvalid)
+	}
+
+	// 期間を決定（省略した場合は現在までの既定の長さ）
+	loc := h.timeZone()
+	from, ok := parseTimeParam(c.QueryParam("from"), false, loc)
+	if !ok {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgFromInvalid)
+	}
+	to, ok := parseTimeParam(c.QueryParam("to"), true, loc)
+	if !ok {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgToInvalid)
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	since := h.AccessLogService.RawLogsSince(time.Now())
+	if from.IsZero() {
+		from = to.Add(-span)
+		// 省略した場合は生のアクセスログが残っている最初の集計単位から始める
+		if from.Before(since) {
+			from = util.TruncateToInterval(since, interval, loc)
+			if from.Before(since) {
+				from = util.AddInterval(from, interval)
+			}
+		}
+	}
+	if !from.Before(to) {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgTimeRangeInvalid)
+	}
+
+	postalCode := c.QueryParam("postal_code")
+	if postalCode != "" && (len(postalCode) != 7 || !isDigits(postalCode)) {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgPostalCodeInvalid)
+	}
+
+	timeseries, err := h.AccessLogService.GetTimeseries(postalCode, interval, from, to, loc)
+	if errors.Is(err, service.ErrTooManyBuckets) {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgTooManyBuckets, service.MaxTimeseriesBuckets)
+	}
+	if errors.Is(err, service.ErrBeforeRetention) {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgFromBeforeRetention, since.In(loc).Format(time.RFC3339))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return respond(c, format, http.StatusOK, timeseries)
+}
+
+// HandleAccessLogRegions は期間内のアクセス数を都道府県または市区町村ごとに返す
+func (h *Handler) HandleAccessLogRegions(c echo.Context) error {
+	// レスポンス形式を決定（対応していない場合は406エラーを返す）
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return notAcceptable(c)
+	}
+
+	level := c.QueryParam("level")
+	if level == "" {
+		level = entity.RegionLevelPrefecture
+	}
+	if level != entity.RegionLevelPrefecture && level != entity.RegionLevelCity {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgRegionLevelInvalid)
+	}
+
+	loc := h.timeZone()
+	from, ok := parseTimeParam(c.QueryParam("from"), false, loc)
+	if !ok {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgFromInvalid)
+	}
+	to, ok := parseTimeParam(c.QueryParam("to"), true, loc)
+	if !ok {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgToInvalid)
+	}
+	// 省略した場合は生のアクセスログが残っている期間を集計する
+	since := h.AccessLogService.RawLogsSince(time.Now())
+	if from.IsZero() {
+		from = since
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgTimeRangeInvalid)
+	}
+
+	regions, err := h.AccessLogService.GetRegionCounts(level, from, to)
+	if errors.Is(err, service.ErrBeforeRetention) {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgFromBeforeRetention, since.In(loc).Format(time.RFC3339))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return respond(c, format, http.StatusOK, regions)
+}
+
+// HandleAccessLogTrending は直近の期間にアクセスの多い郵便番号を、直前の同じ長さの期間からの増加率とともに返す
+func (h *Handler) HandleAccessLogTrending(c echo.Context) error {
+	// レスポンス形式を決定（対応していない場合は406エラーを返す）
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return notAcceptable(c)
+	}
+	trending := h.AccessLogService.Trending
+	if trending == nil {
+		return errorJSON(c, http.StatusServiceUnavailable, i18n.MsgTrendingUnavailable)
+	}
+
+	window := 15 * time.Minute
+	if v := c.QueryParam("window"); v != "" {
+		var err error
+		if window, err = time.ParseDuration(v); err != nil {
+			return errorJSON(c, http.StatusBadRequest, i18n.MsgWindowInvalid, trending.MaxWindow())
+		}
+	}
+	limit, err := queryInt(c, "limit", defaultTrendingLimit)
+	if err != nil || limit < 1 || limit > maxTrendingLimit {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgLimitOutOfRange, maxTrendingLimit)
+	}
+
+	list, err := trending.Top(window, limit, time.Now().In(h.timeZone()))
+	if errors.Is(err, service.ErrWindowOutOfRange) {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgWindowInvalid, trending.MaxWindow())
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return respond(c, format, http.StatusOK, list)
+}
+
+// HandleAccessLogStats はアクセスログの非同期の書き込みとスプールの累計件数を返す
+func (h *Handler) HandleAccessLogStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.AccessLogService.PipelineStats())
+}
+
+// エクスポート形式ごとの Content-Type
+var exportContentTypes = map[string]string{
+	service.ExportCSV:    "text/csv; charset=UTF-8",
+	service.ExportNDJSON: "application/x-ndjson",
+}
+
+// HandleAccessLogExport は期間内の生のアクセスログを CSV または NDJSON でストリーミングして返す
+func (h *Handler) HandleAccessLogExport(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = service.ExportCSV
+	}
+	if !service.IsExportFormat(format) {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgExportFormatInvalid)
+	}
+
+	loc := h.timeZone()
+	from, ok := parseTimeParam(c.QueryParam("from"), false, loc)
+	if !ok {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgFromInvalid)
+	}
+	to, ok := parseTimeParam(c.QueryParam("to"), true, loc)
+	if !ok {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgToInvalid)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgTimeRangeInvalid)
+	}
+
+	// Content-Length を付けずに書き出し、チャンク形式で返す
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, exportContentTypes[format])
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "access_logs."+format))
+	res.WriteHeader(http.StatusOK)
+
+	// 書き出しを始めた後はステータスを変えられないため、エラーはログに残して途中で打ち切る
+	n, err := h.AccessLogService.ExportAccessLogs(c.Request().Context(), res, format, from, to)
+	if err != nil {
+		c.Logger().Errorf("access log export aborted after %d rows: %v", n, err)
+	}
+	return nil
+}
+
+// 日付の解釈と時系列の集計に使うタイムゾーン
+func (h *Handler) timeZone() *time.Location {
+	if h.Cfg != nil && h.Cfg.TimeZone != nil {
+		return h.Cfg.TimeZone
+	}
+	return time.UTC
+}
+
+// 集計結果のページングの位置を URL に使える文字列にする
+func encodeAccessLogCursor(cursor *entity.AccessLogCursor) string {
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeAccessLogCursor(s string) (*entity.AccessLogCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor entity.AccessLogCursor
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,395 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/config"
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/handler"
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flushRecorder は Flush の回数と、そのときまでに書き出された行数を記録する httptest.ResponseRecorder
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushedLines []int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushedLines = append(r.flushedLines, strings.Count(r.Body.String(), "\n"))
+	r.ResponseRecorder.Flush()
+}
+
+// FailingExportRepository は limit 件を返した後に読み出しに失敗する entity.AccessLogRepository
+type FailingExportRepository struct {
+	*infra.MemoryAccessLogRepository
+	limit int
+}
+
+func (m *FailingExportRepository) StreamAccessLogs(ctx context.Context, from, to time.Time, fn func(entity.AccessLogEntry) error) error {
+	n := 0
+	return m.MemoryAccessLogRepository.StreamAccessLogs(ctx, from, to, func(entry entity.AccessLogEntry) error {
+		if n == m.limit {
+			return errors.New("connection lost")
+		}
+		n++
+		return fn(entry)
+	})
+}
+
+// n 件のアクセスログを保存したリポジトリを作る
+func newExportRepository(t *testing.T, n int) *infra.MemoryAccessLogRepository {
+	repo := infra.NewMemoryAccessLogRepository()
+	entries := make([]entity.AccessLogEntry, n)
+	for i := range entries {
+		entries[i] = entity.AccessLogEntry{
+			ID:         fmt.Sprintf("id-%d", i),
+			PostalCode: "1000001",
+			Status:     http.StatusOK,
+			Latency:    1500 * time.Microsecond,
+			CreatedAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Second),
+		}
+	}
+	require.NoError(t, repo.InsertAccessLogs(entries))
+	return repo
+}
+
+func TestHandler_HandleAccessLogExport(t *testing.T) {
+	e := echo.New()
+	h := handler.NewHandler(nil, service.NewAccessLogService(newExportRepository(t, 2)), &config.Config{})
+
+	tests := []struct {
+		name          string
+		query         string
+		contentType   string
+		filename      string
+		expectedLines []string
+	}{
+		{
+			name:        "既定は CSV",
+			query:       "",
+			contentType: "text/csv; charset=UTF-8",
+			filename:    `attachment; filename="access_logs.csv"`,
+			expectedLines: []string{
+				"id,postal_code,status,latency_ms,client_ip,client_id,user_agent,request_id,hit_count,error_class,prefecture_code,city_code,created_at",
+				"id-0,1000001,200,1.5,,,,,0,,,,2024-01-01T00:00:00Z",
+				"id-1,1000001,200,1.5,,,,,0,,,,2024-01-01T00:00:01Z",
+			},
+		},
+		{
+			name:        "NDJSON",
+			query:       "format=ndjson&from=2024-01-01T00:00:01Z",
+			contentType: "application/x-ndjson",
+			filename:    `attachment; filename="access_logs.ndjson"`,
+			expectedLines: []string{
+				`{"id":"id-1","postal_code":"1000001","status":200,"latency_ms":1.5,"client_ip":"","client_id":"","user_agent":"","request_id":"","hit_count":0,"error_class":"","prefecture_code":"","city_code":"","created_at":"2024-01-01T00:00:01Z"}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/address/access_logs/export?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := h.HandleAccessLogExport(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, tt.filename, rec.Header().Get(echo.HeaderContentDisposition))
+			assert.Empty(t, rec.Header().Get(echo.HeaderContentLength))
+			assert.Equal(t, strings.Join(tt.expectedLines, "\n")+"\n", rec.Body.String())
+		})
+	}
+}
+
+func TestHandler_HandleAccessLogExportInvalidParams(t *testing.T) {
+	e := echo.New()
+	h := handler.NewHandler(nil, service.NewAccessLogService(infra.NewMemoryAccessLogRepository()), &config.Config{})
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedBody string
+	}{
+		{name: "形式が不正", query: "format=xml", expectedBody: `{"error":"format must be csv or ndjson"}`},
+		{name: "開始日時が不正", query: "from=yesterday", expectedBody: `{"error":"from must be an RFC 3339 date-time or a YYYY-MM-DD date"}`},
+		{name: "期間が逆", query: "from=2024-01-02&to=2024-01-01", expectedBody: `{"error":"from must be before to"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/address/access_logs/export?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := h.HandleAccessLogExport(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestHandler_HandleAccessLogExportStreamsInChunks(t *testing.T) {
+	e := echo.New()
+	h := handler.NewHandler(nil, service.NewAccessLogService(newExportRepository(t, 2500)), &config.Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/address/access_logs/export?format=ndjson", nil)
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	c := e.NewContext(req, rec)
+
+	err := h.HandleAccessLogExport(c)
+	assert.NoError(t, err)
+
+	// 1000 件ごとと最後に送る
+	assert.Equal(t, []int{1000, 2000, 2500}, rec.flushedLines)
+}
+
+func TestHandler_HandleAccessLogExportAbortsOnError(t *testing.T) {
+	e := echo.New()
+	repo := &FailingExportRepository{MemoryAccessLogRepository: newExportRepository(t, 1500), limit: 1200}
+	h := handler.NewHandler(nil, service.NewAccessLogService(repo), &config.Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/address/access_logs/export?format=ndjson", nil)
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	c := e.NewContext(req, rec)
+
+	// 書き出しを始めた後のエラーではステータスを変えず、送った分で打ち切る
+	err := h.HandleAccessLogExport(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []int{1000}, rec.flushedLines)
+	assert.Contains(t, rec.Body.String(), `"id":"id-999"`)
+	assert.NotContains(t, rec.Body.String(), `"id":"id-1200"`)
+}
+
+func TestHandler_HandleAccessLogTrendingUnavailable(t *testing.T) {
+	e := echo.New()
+	h := handler.NewHandler(nil, service.NewAccessLogService(infra.NewMemoryAccessLogRepository()), &config.Config{})
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedBody string
+	}{
+		{name: "英語", query: "", expectedBody: `{"error":"trending is not available"}`},
+		{name: "日本語", query: "lang=ja", expectedBody: `{"error":"アクセスの多い郵便番号の集計は利用できません"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/address/access_logs/trending?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := h.HandleAccessLogTrending(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+// RecordingAccessLogRepository は集計条件を記録する entity.AccessLogRepository
+type RecordingAccessLogRepository struct {
+	*infra.MemoryAccessLogRepository
+	query entity.AccessLogQuery
+}
+
+func (m *RecordingAccessLogRepository) GetAccessLogs(q entity.AccessLogQuery) ([]entity.AccessLog, error) {
+	m.query = q
+	return m.MemoryAccessLogRepository.GetAccessLogs(q)
+}
+
+func TestHandler_HandleAccessLogsParsesQuery(t *testing.T) {
+	e := echo.New()
+	jst, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	repo := &RecordingAccessLogRepository{MemoryAccessLogRepository: infra.NewMemoryAccessLogRepository()}
+	h := handler.NewHandler(nil, service.NewAccessLogService(repo), &config.Config{TimeZone: jst})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected entity.AccessLogQuery
+	}{
+		{
+			name:     "既定値",
+			query:    "",
+			expected: entity.AccessLogQuery{Sort: entity.AccessLogSortCount, Order: entity.OrderDesc, Limit: 101},
+		},
+		{
+			name:     "郵便番号順は昇順",
+			query:    "sort=postal_code&prefix=100&limit=10",
+			expected: entity.AccessLogQuery{Prefix: "100", Sort: entity.AccessLogSortPostalCode, Order: entity.OrderAsc, Limit: 11},
+		},
+		{
+			name:     "並び順を指定",
+			query:    "sort=last_seen&order=asc",
+			expected: entity.AccessLogQuery{Sort: entity.AccessLogSortLastSeen, Order: entity.OrderAsc, Limit: 101},
+		},
+		{
+			// 日付はタイムゾーンの 0 時、to はその日を含む
+			name:  "日付で期間を指定",
+			query: "from=2024-01-01&to=2024-01-31",
+			expected: entity.AccessLogQuery{
+				From: time.Date(2024, 1, 1, 0, 0, 0, 0, jst), To: time.Date(2024, 2, 1, 0, 0, 0, 0, jst),
+				Sort: entity.AccessLogSortCount, Order: entity.OrderDesc, Limit: 101,
+			},
+		},
+		{
+			name:  "RFC 3339 で期間を指定",
+			query: "from=2024-01-01T09:00:00Z&to=2024-01-01T10:00:00Z",
+			expected: entity.AccessLogQuery{
+				From: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), To: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+				Sort: entity.AccessLogSortCount, Order: entity.OrderDesc, Limit: 101,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/address/access_logs?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := h.HandleAccessLogs(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.True(t, tt.expected.From.Equal(repo.query.From), repo.query.From)
+			assert.True(t, tt.expected.To.Equal(repo.query.To), repo.query.To)
+			tt.expected.From, tt.expected.To = repo.query.From, repo.query.To
+			assert.Equal(t, tt.expected, repo.query)
+		})
+	}
+}
+
+func TestHandler_HandleAccessLogsInvalidQuery(t *testing.T) {
+	e := echo.New()
+	h := handler.NewHandler(nil, service.NewAccessLogService(infra.NewMemoryAccessLogRepository()), &config.Config{})
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedBody string
+	}{
+		{name: "開始日時が不正", query: "from=2024/01/01", expectedBody: `{"error":"from must be an RFC 3339 date-time or a YYYY-MM-DD date"}`},
+		{name: "終了日時が不正", query: "to=tomorrow", expectedBody: `{"error":"to must be an RFC 3339 date-time or a YYYY-MM-DD date"}`},
+		{name: "期間が逆", query: "from=2024-01-02T00:00:00Z&to=2024-01-02T00:00:00Z", expectedBody: `{"error":"from must be before to"}`},
+		{name: "prefix が数字でない", query: "prefix=1a", expectedBody: `{"error":"prefix must be 1 to 7 digits"}`},
+		{name: "prefix が長すぎる", query: "prefix=12345678", expectedBody: `{"error":"prefix must be 1 to 7 digits"}`},
+		{name: "sort が不正", query: "sort=random", expectedBody: `{"error":"sort must be one of count, postal_code or last_seen"}`},
+		{name: "order が不正", query: "order=up", expectedBody: `{"error":"order must be asc or desc"}`},
+		{name: "cursor が base64 でない", query: "cursor=!!!", expectedBody: `{"error":"cursor is invalid or does not match the sort order"}`},
+		{name: "cursor が JSON でない", query: "cursor=eA", expectedBody: `{"error":"cursor is invalid or does not match the sort order"}`},
+		{name: "limit が範囲外", query: "limit=1001", expectedBody: `{"error":"limit must be between 1 and 1000"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/address/access_logs?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := h.HandleAccessLogs(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestHandler_HandleAccessLogsCursor(t *testing.T) {
+	e := echo.New()
+	repo := infra.NewMemoryAccessLogRepository()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, postalCode := range []string{"1000001", "1000002", "1000002", "1000003", "1000003", "1000003"} {
+		require.NoError(t, repo.InsertAccessLog(entity.AccessLogEntry{PostalCode: postalCode, CreatedAt: now.Add(time.Duration(i) * time.Minute)}))
+	}
+	h := handler.NewHandler(nil, service.NewAccessLogService(repo), &config.Config{})
+
+	get := func(query string) (int, entity.AccessLogList) {
+		req := httptest.NewRequest(http.MethodGet, "/address/access_logs?"+query, nil)
+		rec := httptest.NewRecorder()
+		require.NoError(t, h.HandleAccessLogs(e.NewContext(req, rec)))
+		var list entity.AccessLogList
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+		}
+		return rec.Code, list
+	}
+	postalCodes := func(list entity.AccessLogList) []string {
+		var codes []string
+		for _, log := range list.AccessLogs {
+			codes = append(codes, log.PostalCode)
+		}
+		return codes
+	}
+
+	// 次のページの cursor で続きを取得し、最後のページには cursor を付けない
+	status, first := get("limit=2")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"1000003", "1000002"}, postalCodes(first))
+	require.NotEmpty(t, first.NextCursor)
+
+	status, second := get("limit=2&cursor=" + first.NextCursor)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"1000001"}, postalCodes(second))
+	assert.Empty(t, second.NextCursor)
+
+	// 並び順の異なる cursor は使えない
+	status, _ = get("limit=2&sort=postal_code&cursor=" + first.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = get("limit=2&order=asc&cursor=" + first.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// cursor は URL に使える文字だけで表す
+	_, err := base64.RawURLEncoding.DecodeString(first.NextCursor)
+	assert.NoError(t, err)
+}
+
+func TestHandler_HandleAccessLogsAdjustsRolledUpDays(t *testing.T) {
+	e := echo.New()
+	accessLogService := service.NewAccessLogService(infra.NewMemoryAccessLogRepository())
+	accessLogService.Retention = service.NewAccessLogRetention(nil, 3, 10)
+	h := handler.NewHandler(nil, accessLogService, &config.Config{})
+	day := service.RetentionCutoff(time.Now(), 3).AddDate(0, 0, -2)
+
+	get := func(from, to time.Time) entity.AccessLogList {
+		query := "from=" + from.Format(time.RFC3339) + "&to=" + to.Format(time.RFC3339)
+		req := httptest.NewRequest(http.MethodGet, "/address/access_logs?"+query, nil)
+		rec := httptest.NewRecorder()
+		require.NoError(t, h.HandleAccessLogs(e.NewContext(req, rec)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var list entity.AccessLogList
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+		return list
+	}
+
+	// 日次に集約した日の途中を指定した場合は、日の境界に広げた期間を返す
+	list := get(day.Add(12*time.Hour), day.Add(13*time.Hour))
+	if assert.NotNil(t, list.AdjustedFrom) && assert.NotNil(t, list.AdjustedTo) {
+		assert.True(t, day.Equal(*list.AdjustedFrom), list.AdjustedFrom)
+		assert.True(t, day.AddDate(0, 0, 1).Equal(*list.AdjustedTo), list.AdjustedTo)
+	}
+
+	// 日の境界を指定した場合は広げない
+	list = get(day, day.AddDate(0, 0, 1))
+	assert.Nil(t, list.AdjustedFrom)
+	assert.Nil(t, list.AdjustedTo)
+}
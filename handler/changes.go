@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/i18n"
+	"github.com/labstack/echo/v4"
+)
+
+// HandleAddressChanges は指定した年月以降に追加・削除・変更された郵便番号を返す
+func (h *Handler) HandleAddressChanges(c echo.Context) error {
+	since := c.QueryParam("since")
+	if _, err := time.Parse("2006-01", since); err != nil {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgSinceInvalid)
+	}
+
+	changes, err := h.DatasetService.Changes(since)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, changes)
+}
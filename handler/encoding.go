@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/i18n"
+	"github.com/dkpcb/finatext_kadai_2/util"
+	"github.com/labstack/echo/v4"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// レスポンスのエンコード形式
+type responseFormat string
+
+const (
+	formatJSON    responseFormat = "json"
+	formatXML     responseFormat = "xml"
+	formatCSV     responseFormat = "csv"
+	formatMsgPack responseFormat = "msgpack"
+)
+
+// 各形式の Content-Type
+var formatContentTypes = map[responseFormat]string{
+	formatJSON:    echo.MIMEApplicationJSONCharsetUTF8,
+	formatXML:     echo.MIMEApplicationXMLCharsetUTF8,
+	formatCSV:     "text/csv; charset=UTF-8",
+	formatMsgPack: echo.MIMEApplicationMsgpack,
+}
+
+// Accept ヘッダーのメディアタイプと形式の対応
+var mediaTypeFormats = map[string]responseFormat{
+	"application/json":        formatJSON,
+	"application/xml":         formatXML,
+	"text/xml":                formatXML,
+	"text/csv":                formatCSV,
+	"application/msgpack":     formatMsgPack,
+	"application/x-msgpack":   formatMsgPack,
+	"application/vnd.msgpack": formatMsgPack,
+}
+
+// ?format= または Accept ヘッダーからレスポンス形式を決定
+func negotiateFormat(c echo.Context) (responseFormat, bool) {
+	if format := c.QueryParam("format"); format != "" {
+		f := responseFormat(strings.ToLower(format))
+		_, ok := formatContentTypes[f]
+		return f, ok
+	}
+
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, true
+	}
+
+	// q 値の高い順に対応可能なメディアタイプを探す
+	for _, mediaType := range util.ParseQualityList(accept) {
+		switch {
+		case mediaType == "*/*" || mediaType == "application/*":
+			return formatJSON, true
+		case mediaType == "text/*":
+			return formatCSV, true
+		}
+		if f, ok := mediaTypeFormats[mediaType]; ok {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// 対応していない形式が要求された場合の 406 レスポンス
+func notAcceptable(c echo.Context) error {
+	return c.JSON(http.StatusNotAcceptable, map[string]interface{}{
+		"error":     i18n.Message(requestLang(c), i18n.MsgUnsupportedFormat),
+		"supported": []string{"application/json", "application/xml", "text/csv", "application/msgpack"},
+	})
+}
+
+// 指定した形式でレスポンスをエンコードして返す
+func respond(c echo.Context, format responseFormat, status int, v interface{}) error {
+	switch format {
+	case formatXML:
+		return c.XML(status, v)
+	case formatCSV:
+		records, err := csvRecords(v)
+		if err != nil {
+			return err
+		}
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, formatContentTypes[formatCSV])
+		res.WriteHeader(status)
+		w := csv.NewWriter(res)
+		if err := w.WriteAll(records); err != nil {
+			return err
+		}
+		return w.Error()
+	case formatMsgPack:
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, formatContentTypes[formatMsgPack])
+		res.WriteHeader(status)
+		enc := msgpack.NewEncoder(res)
+		// JSON と同じフィールド名でエンコード
+		enc.SetCustomStructTag("json")
+		return enc.Encode(v)
+	default:
+		return c.JSON(status, v)
+	}
+}
+
+// レスポンスを CSV のヘッダーと行に変換
+func csvRecords(v interface{}) ([][]string, error) {
+	switch v := v.(type) {
+	case *entity.Address:
+		return [][]string{
+			{"postal_code", "hit_count", "address", "tokyo_sta_distance", "prefecture_code", "municipality_code", "superseded_by"},
+			{v.PostalCode, strconv.Itoa(v.HitCount), v.CommonAddress, formatFloat(v.TokyoStaDistance), v.PrefectureCode, v.MunicipalityCode, v.SupersededBy},
+		}, nil
+	case *entity.AccessLogList:
+		records := [][]string{{"postal_code", "request_count", "first_seen", "last_seen", "unique_clients"}}
+		for _, log := range v.AccessLogs {
+			records = append(records, []string{log.PostalCode, strconv.Itoa(log.RequestCount),
+				log.FirstSeen.Format(time.RFC3339), log.LastSeen.Format(time.RFC3339), strconv.Itoa(log.UniqueClients)})
+		}
+		return records, nil
+	case *entity.AccessLogRegionList:
+		records := [][]string{{"code", "name", "prefecture_code", "prefecture", "request_count", "share"}}
+		for _, region := range v.Regions {
+			records = append(records, []string{region.Code, region.Name, region.PrefectureCode, region.Prefecture,
+				strconv.Itoa(region.RequestCount), formatFloat(region.Share)})
+		}
+		return records, nil
+	case *entity.TrendingList:
+		records := [][]string{{"postal_code", "request_count", "previous_count", "growth"}}
+		for _, t := range v.PostalCodes {
+			growth := ""
+			if t.Growth != nil {
+				growth = formatFloat(*t.Growth)
+			}
+			records = append(records, []string{t.PostalCode, strconv.Itoa(t.RequestCount), strconv.Itoa(t.PreviousCount), growth})
+		}
+		return records, nil
+	case *entity.DistanceMatrix:
+		// 1 セル 1 行
+		records := [][]string{{"origin", "destination", "distance_km", "error"}}
+		for _, row := range v.Rows {
+			for _, cell := range row {
+				distance := ""
+				if cell.DistanceKm != nil {
+					distance = strconv.FormatFloat(*cell.DistanceKm, 'f', 1, 64)
+				}
+				records = append(records, []string{cell.Origin, cell.Destination, distance, cell.Error})
+			}
+		}
+		return records, nil
+	case *entity.AccessLogTimeseries:
+		records := [][]string{{"start", "count"}}
+		for _, bucket := range v.Buckets {
+			records = append(records, []string{bucket.Start.Format(time.RFC3339), strconv.Itoa(bucket.Count)})
+		}
+		return records, nil
+	}
+	return nil, fmt.Errorf("csv encoding is not supported for %T", v)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,189 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/config"
+	"github.com/dkpcb/finatext_kadai_2/handler"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// 住所検索のハンドラーを作成
+func newAddressHandler() *handler.Handler {
+	cfg := &config.Config{ExternalAPI: "https://example.com"}
+	addressService := service.NewAddressService(&MockAddressRepository{}, cfg.ExternalAPI)
+	return handler.NewHandler(addressService, service.NewAccessLogService(NewMockAccessLogRepository()), cfg)
+}
+
+func TestHandler_NegotiateFormat(t *testing.T) {
+	e := echo.New()
+	h := newAddressHandler()
+
+	tests := []struct {
+		name           string
+		query          string
+		accept         string
+		expectedStatus int
+		contentType    string
+	}{
+		{name: "指定なしは JSON", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationJSON},
+		{name: "format パラメータを優先", query: "&format=XML", accept: "text/csv", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationXMLCharsetUTF8},
+		{name: "メディアタイプ", accept: "text/csv", expectedStatus: http.StatusOK, contentType: "text/csv; charset=UTF-8"},
+		{name: "パラメータ付きのメディアタイプ", accept: "application/vnd.msgpack; charset=binary", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationMsgpack},
+		{name: "q 値の高い形式を選択", accept: "application/json;q=0.5, text/xml;q=0.9", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationXMLCharsetUTF8},
+		{name: "q 値が同じ場合は先に書いた形式", accept: "text/csv, application/json", expectedStatus: http.StatusOK, contentType: "text/csv; charset=UTF-8"},
+		{name: "q=0 の形式は選ばない", accept: "text/csv;q=0, application/xml;q=0.1", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationXMLCharsetUTF8},
+		{name: "未対応の形式は読み飛ばす", accept: "image/png, application/msgpack;q=0.5", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationMsgpack},
+		{name: "*/* は JSON", accept: "image/png, */*;q=0.1", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationJSON},
+		{name: "application/* は JSON", accept: "application/*", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationJSON},
+		{name: "text/* は CSV", accept: "text/*", expectedStatus: http.StatusOK, contentType: "text/csv; charset=UTF-8"},
+		{name: "対応する形式がない", accept: "image/png, text/html", expectedStatus: http.StatusNotAcceptable, contentType: echo.MIMEApplicationJSON},
+		{name: "すべて q=0", accept: "application/json;q=0", expectedStatus: http.StatusNotAcceptable, contentType: echo.MIMEApplicationJSON},
+		{name: "未対応の format パラメータ", query: "&format=yaml", expectedStatus: http.StatusNotAcceptable, contentType: echo.MIMEApplicationJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/address?postal_code=5016121"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := h.HandleAddress(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get(echo.HeaderContentType))
+		})
+	}
+}
+
+func TestHandler_NotAcceptableBody(t *testing.T) {
+	e := echo.New()
+	h := newAddressHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/address?postal_code=5016121&lang=ja", nil)
+	req.Header.Set(echo.HeaderAccept, "image/png")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := h.HandleAddress(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	assert.JSONEq(t, `{"error":"対応していないレスポンス形式です","supported":["application/json","application/xml","text/csv","application/msgpack"]}`, rec.Body.String())
+}
+
+func TestHandler_RespondEncodings(t *testing.T) {
+	e := echo.New()
+	h := newAddressHandler()
+
+	get := func(format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/address?postal_code=5016121&format="+format, nil)
+		rec := httptest.NewRecorder()
+		require.NoError(t, h.HandleAddress(e.NewContext(req, rec)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec
+	}
+
+	t.Run("XML", func(t *testing.T) {
+		rec := get("xml")
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+			`<address><postal_code>5016121</postal_code><hit_count>1</hit_count><address>岐阜県岐阜市柳津町</address><tokyo_sta_distance>277.7</tokyo_sta_distance></address>`,
+			rec.Body.String())
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		rec := get("csv")
+		assert.Equal(t, "postal_code,hit_count,address,tokyo_sta_distance,prefecture_code,municipality_code,superseded_by\n"+
+			"5016121,1,岐阜県岐阜市柳津町,277.7,,,\n", rec.Body.String())
+	})
+
+	t.Run("MessagePack", func(t *testing.T) {
+		rec := get("msgpack")
+		// JSON と同じフィールド名でエンコードする
+		var body map[string]interface{}
+		require.NoError(t, msgpack.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "5016121", body["postal_code"])
+		assert.EqualValues(t, 1, body["hit_count"])
+		assert.Equal(t, "岐阜県岐阜市柳津町", body["address"])
+		assert.Equal(t, 277.7, body["tokyo_sta_distance"])
+		assert.NotContains(t, body, "XMLName")
+		assert.NotContains(t, body, "prefecture_code")
+	})
+}
+
+func TestHandler_RespondAccessLogsCSV(t *testing.T) {
+	e := echo.New()
+	h := handler.NewHandler(nil, service.NewAccessLogService(NewMockAccessLogRepository()), &config.Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/address/access_logs", nil)
+	req.Header.Set(echo.HeaderAccept, "text/csv")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := h.HandleAccessLogs(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "postal_code,request_count,first_seen,last_seen,unique_clients\n"+
+		"1020073,7,0001-01-01T00:00:00Z,0001-01-01T00:00:00Z,0\n"+
+		"1000001,5,0001-01-01T00:00:00Z,0001-01-01T00:00:00Z,0\n"+
+		"5300001,2,0001-01-01T00:00:00Z,0001-01-01T00:00:00Z,0\n", rec.Body.String())
+}
+
+func TestHandler_HandleDistanceMatrixFormats(t *testing.T) {
+	e := echo.New()
+	h := newAddressHandler()
+	h.DistanceService = service.NewDistanceService(&MockAddressRepository{}, 1, time.Minute)
+	body := `{"origins":["5016121"],"destinations":["5016121","9999999"]}`
+
+	tests := []struct {
+		name           string
+		query          string
+		accept         string
+		expectedStatus int
+		contentType    string
+	}{
+		{name: "指定なしは JSON", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationJSON},
+		{name: "q 値の高い JSON を選択", accept: "text/csv;q=0.9, application/json", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationJSON},
+		{name: "CSV", accept: "text/csv", expectedStatus: http.StatusOK, contentType: "text/csv; charset=UTF-8"},
+		{name: "format パラメータを優先", query: "?format=csv", accept: "application/json", expectedStatus: http.StatusOK, contentType: "text/csv; charset=UTF-8"},
+		{name: "XML", query: "?format=xml", expectedStatus: http.StatusOK, contentType: echo.MIMEApplicationXMLCharsetUTF8},
+		{name: "対応する形式がない", accept: "image/png", expectedStatus: http.StatusNotAcceptable, contentType: echo.MIMEApplicationJSON},
+		{name: "未対応の format パラメータ", query: "?format=yaml", expectedStatus: http.StatusNotAcceptable, contentType: echo.MIMEApplicationJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/distance/matrix"+tt.query, strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := h.HandleDistanceMatrix(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get(echo.HeaderContentType))
+		})
+	}
+
+	// CSV は 1 セル 1 行
+	req := httptest.NewRequest(http.MethodPost, "/distance/matrix?format=csv", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.HandleDistanceMatrix(e.NewContext(req, rec)))
+	assert.Equal(t, "origin,destination,distance_km,error\n"+
+		"5016121,5016121,0.0,\n"+
+		"5016121,9999999,,destination 9999999: address not found\n", rec.Body.String())
+}
@@ -1,18 +1,40 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/dkpcb/finatext_kadai_2/config"
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/i18n"
 	"github.com/dkpcb/finatext_kadai_2/service"
 
 	"github.com/labstack/echo/v4"
 )
 
+// GeoJSON のメディアタイプ
+const mimeGeoJSON = "application/geo+json"
+
 type Handler struct {
 	AddressService   *service.AddressService
 	AccessLogService *service.AccessLogService
+	DistanceService  *service.DistanceService
+	NearbyService    *service.NearbyService
+	RegionService    *service.RegionService
+	DatasetService   *service.DatasetService
+	LookupHub        *service.LookupHub // 住所検索の結果のライブストリーム（任意）
+	Readiness        ReadinessChecker   // /readyz で確認する依存先（nil の場合は常に ready）
 	Cfg              *config.Config
+
+	notReady atomic.Bool // 直近の /readyz で ready でなかったか（状態が変わったときだけログに出す）
 }
 
 // 新しい Handler を作成
@@ -27,8 +49,23 @@ func NewHandler(addressService *service.AddressService, accessLogService *servic
 // ルーティングを登録
 func (h *Handler) RegisterRoutes(e *echo.Echo) {
 	e.GET("/", h.HandleRoot)
+	e.GET("/healthz", h.HandleHealthz)
+	e.GET("/readyz", h.HandleReadyz)
 	e.GET("/address", h.HandleAddress)
 	e.GET("/address/access_logs", h.HandleAccessLogs)
+	e.GET("/address/access_logs/timeseries", h.HandleAccessLogTimeseries)
+	e.GET("/address/access_logs/export", h.HandleAccessLogExport)
+	e.GET("/address/access_logs/regions", h.HandleAccessLogRegions)
+	e.GET("/address/access_logs/stream", h.HandleLookupStream)
+	e.GET("/address/access_logs/trending", h.HandleAccessLogTrending)
+	e.GET("/address/access_logs/stats", h.HandleAccessLogStats)
+	e.GET("/address/nearby", h.HandleNearby)
+	e.GET("/address/changes", h.HandleAddressChanges)
+	e.POST("/distance/matrix", h.HandleDistanceMatrix)
+	e.GET("/prefectures", h.HandlePrefectures)
+	e.GET("/prefectures/:code/cities", h.HandleCities)
+	e.GET("/cities/:code/towns", h.HandleTowns)
+	e.GET("/municipalities/:code", h.HandleMunicipality)
 }
 
 // ルートエンドポイントを処理
@@ -40,46 +77,277 @@ func (h *Handler) HandleRoot(c echo.Context) error {
 
 // HandleAddress は住所検索のエンドポイントを処理
 func (h *Handler) HandleAddress(c echo.Context) error {
+	start := time.Now()
+
 	// クエリパラメータから郵便番号を取得
 	postalCode := c.QueryParam("postal_code")
 	if postalCode == "" {
 		// 郵便番号がない場合は400エラーを返す
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "postal_code is required"})
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgPostalCodeRequired)
+	}
+
+	// レスポンス形式を決定（対応していない場合は406エラーを返す）
+	geoJSON := wantsGeoJSON(c)
+	format, ok := negotiateFormat(c)
+	if !geoJSON && !ok {
+		return notAcceptable(c)
 	}
 
-	// アクセスログを保存
-	if err := h.AccessLogService.SaveAccessLog(postalCode); err != nil {
-		// ログ保存でエラーが発生した場合は500エラーを返す
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to save access log"})
+	// サービス層で住所データを取得（GeoJSON が要求された場合は地点ごとの Feature）
+	var address *entity.Address
+	var collection *entity.FeatureCollection
+	var err error
+	if geoJSON {
+		collection, err = h.AddressService.GetAddressGeoJSON(postalCode)
+	} else {
+		address, err = h.AddressService.GetLocalizedAddress(postalCode, requestLang(c))
+	}
+
+	// 検索結果を含むアクセスログを保存
+	entry := accessLogEntry(c, postalCode, start)
+	entry.ClientID = h.AccessLogService.ClientID(clientKey(c))
+	switch {
+	case err != nil:
+		entry.Status, entry.ErrorClass = http.StatusInternalServerError, entity.ErrorClassUpstream
+	case address == nil && collection == nil:
+		entry.Status, entry.ErrorClass = http.StatusNotFound, entity.ErrorClassNotFound
+	case address != nil:
+		entry.Status, entry.HitCount = http.StatusOK, address.HitCount
+	default:
+		// 重心の Feature を除いた地点の数
+		entry.Status, entry.HitCount = http.StatusOK, len(collection.Features)-1
+	}
+	h.publishLookup(entry, address)
+	if err := h.AccessLogService.SaveAccessLog(entry); err != nil {
+		// ログの保存に失敗しても、記録したステータスと一致するよう検索結果をそのまま返す
+		log.Printf("Failed to save access log for postalCode: %s, error: %v", postalCode, err)
 	}
 
-	// サービス層で住所データを取得
-	address, err := h.AddressService.GetAddress(postalCode)
 	if err != nil {
 		// サービス層でエラーが発生した場合は500エラーを返す
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-
-	if address == nil {
+	if address == nil && collection == nil {
 		// 該当する住所がない場合は404エラーを返す
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "address not found"})
+		return errorJSON(c, http.StatusNotFound, i18n.MsgAddressNotFound)
+	}
+
+	if geoJSON {
+		c.Response().Header().Set(echo.HeaderContentType, mimeGeoJSON)
+		c.Response().WriteHeader(http.StatusOK)
+		return json.NewEncoder(c.Response()).Encode(collection)
+	}
+
+	// 廃止された郵便番号の場合は後継をヘッダーでも通知する
+	if address.SupersededBy != "" {
+		c.Response().Header().Set("Deprecation", "true")
+		c.Response().Header().Set("Link", fmt.Sprintf(`</address?postal_code=%s>; rel="successor-version"`, address.SupersededBy))
 	}
 
 	// 正常時は200 OKと住所データを返す
-	return c.JSON(http.StatusOK, address)
+	return respond(c, format, http.StatusOK, address)
+}
+
+// リクエストの情報からアクセスログを作成（ステータスと検索結果は呼び出し側で設定）
+func accessLogEntry(c echo.Context, postalCode string, start time.Time) entity.AccessLogEntry {
+	requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		requestID = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+	return entity.AccessLogEntry{
+		PostalCode: postalCode,
+		Latency:    time.Since(start),
+		ClientIP:   c.RealIP(),
+		UserAgent:  c.Request().UserAgent(),
+		RequestID:  requestID,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// クライアントを識別する値（API キーがあればその値、なければ IP アドレス）
+func clientKey(c echo.Context) string {
+	if key := c.Request().Header.Get("X-API-Key"); key != "" {
+		return "key:" + key
+	}
+	if ip := c.RealIP(); ip != "" {
+		return "ip:" + ip
+	}
+	return ""
+}
+
+// GeoJSON 形式のレスポンスが要求されているかを判定
+func wantsGeoJSON(c echo.Context) bool {
+	if format := c.QueryParam("format"); format != "" {
+		return format == "geojson"
+	}
+	return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), mimeGeoJSON)
 }
 
 // アクセスログを集計して返す
 func (h *Handler) HandleAccessLogs(c echo.Context) error {
+	// レスポンス形式を決定（対応していない場合は406エラーを返す）
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return notAcceptable(c)
+	}
+
+	// 集計条件を検証
+	query, msg := parseAccessLogQuery(c, h.timeZone())
+	if msg != "" {
+		return errorJSON(c, http.StatusBadRequest, msg)
+	}
+	limit, err := queryInt(c, "limit", defaultAccessLogLimit)
+	if err != nil || limit < 1 || limit > maxAccessLogLimit {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgLimitOutOfRange, maxAccessLogLimit)
+	}
+	query.Limit = limit
+
+	// 日次に集約した日の途中を指定した場合は日の境界に広げ、広げた期間をレスポンスで知らせる
+	from, to := h.AccessLogService.CoveredRange(query.From, query.To, time.Now())
+	list := &entity.AccessLogList{}
+	if !from.Equal(query.From) {
+		list.AdjustedFrom = &from
+	}
+	if !to.Equal(query.To) {
+		list.AdjustedTo = &to
+	}
+	query.From, query.To = from, to
+
 	// アクセスログの集計結果を取得
-	logs, err := h.AccessLogService.GetAccessLogs()
+	logs, next, err := h.AccessLogService.GetAccessLogs(query)
 	if err != nil {
 		// エラーが発生した場合は500エラーを返す
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
 	// 正常時は200 OKとアクセスログを返す
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"access_logs": logs,
-	})
+	list.AccessLogs = logs
+	if next != nil {
+		list.NextCursor = encodeAccessLogCursor(next)
+	}
+	return respond(c, format, http.StatusOK, list)
+}
+
+// HandleDistanceMatrix は複数の郵便番号間の距離行列を返す
+func (h *Handler) HandleDistanceMatrix(c echo.Context) error {
+	// レスポンス形式を決定（対応していない場合は406エラーを返す）
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return notAcceptable(c)
+	}
+
+	var req entity.DistanceMatrixRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgInvalidRequestBody)
+	}
+
+	if len(req.Origins) == 0 || len(req.Destinations) == 0 {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgOriginsDestinationsMissing)
+	}
+
+	// セル数が上限を超える場合は400エラーを返す
+	if h.Cfg.MatrixMaxCells > 0 && len(req.Origins)*len(req.Destinations) > h.Cfg.MatrixMaxCells {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgMatrixTooLarge, h.Cfg.MatrixMaxCells)
+	}
+
+	matrix := h.DistanceService.BuildMatrix(req.Origins, req.Destinations)
+	return respond(c, format, http.StatusOK, matrix)
+}
+
+// 半径検索のページングの既定値と上限
+const (
+	defaultNearbyLimit = 50
+	maxNearbyLimit     = 500
+)
+
+// HandleNearby は指定した地点または郵便番号から半径内の郵便番号を返す
+func (h *Handler) HandleNearby(c echo.Context) error {
+	if h.NearbyService == nil || !h.NearbyService.Loaded() {
+		return errorJSON(c, http.StatusServiceUnavailable, i18n.MsgDatasetNotLoaded)
+	}
+
+	radiusKm, ok := parseFiniteFloat(c.QueryParam("radius_km"))
+	if !ok || radiusKm <= 0 {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgRadiusInvalid)
+	}
+	if h.Cfg.NearbyMaxRadiusKm > 0 && radiusKm > h.Cfg.NearbyMaxRadiusKm {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgRadiusTooLarge, h.Cfg.NearbyMaxRadiusKm)
+	}
+
+	limit, err := queryInt(c, "limit", defaultNearbyLimit)
+	if err != nil || limit < 1 || limit > maxNearbyLimit {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgLimitOutOfRange, maxNearbyLimit)
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgOffsetInvalid)
+	}
+
+	// 中心座標を決定（郵便番号または緯度経度）
+	var center *entity.GeoPoint
+	if postalCode := c.QueryParam("postal_code"); postalCode != "" {
+		center, err = h.locatePostalCode(postalCode)
+		if errors.Is(err, service.ErrInvalidPostalCode) {
+			return errorJSON(c, http.StatusBadRequest, i18n.MsgPostalCodeInvalid)
+		}
+		if errors.Is(err, service.ErrAddressNotFound) {
+			return errorJSON(c, http.StatusNotFound, i18n.MsgAddressNotFound)
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+	} else {
+		var msg i18n.MessageKey
+		center, msg = parseLatLon(c.QueryParam("lat"), c.QueryParam("lon"))
+		if msg != "" {
+			return errorJSON(c, http.StatusBadRequest, msg)
+		}
+	}
+
+	return c.JSON(http.StatusOK, h.NearbyService.Search(*center, radiusKm, limit, offset))
+}
+
+// 郵便番号の座標をローカルデータセット、なければ外部 API から取得
+func (h *Handler) locatePostalCode(postalCode string) (*entity.GeoPoint, error) {
+	if point, ok := h.NearbyService.Locate(postalCode); ok {
+		return point, nil
+	}
+	if h.DistanceService == nil {
+		return nil, service.ErrAddressNotFound
+	}
+	return h.DistanceService.ResolvePoint(postalCode)
+}
+
+// 緯度経度のクエリパラメータを検証して変換（不正な場合はメッセージのキーを返す）
+func parseLatLon(latParam, lonParam string) (*entity.GeoPoint, i18n.MessageKey) {
+	if latParam == "" || lonParam == "" {
+		return nil, i18n.MsgLocationRequired
+	}
+	lat, ok := parseFiniteFloat(latParam)
+	if !ok || lat < -90 || lat > 90 {
+		return nil, i18n.MsgLatInvalid
+	}
+	lon, ok := parseFiniteFloat(lonParam)
+	if !ok || lon < -180 || lon > 180 {
+		return nil, i18n.MsgLonInvalid
+	}
+	return &entity.GeoPoint{Lat: lat, Lon: lon}, ""
+}
+
+// 数値のクエリパラメータを変換（NaN と無限大は範囲の比較をすり抜けるため不正とする）
+func parseFiniteFloat(v string) (float64, bool) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// 整数のクエリパラメータを取得（未指定の場合は既定値）
+func queryInt(c echo.Context, name string, defaultValue int) (int, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(v)
 }
@@ -1,6 +1,7 @@
 package handler_test
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +11,7 @@ import (
 	"github.com/dkpcb/finatext_kadai_2/config"
 	"github.com/dkpcb/finatext_kadai_2/entity"
 	"github.com/dkpcb/finatext_kadai_2/handler"
+	"github.com/dkpcb/finatext_kadai_2/infra"
 	"github.com/dkpcb/finatext_kadai_2/service"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -30,17 +32,23 @@ func (m *MockAddressRepository) FetchAddressData(postalCode string) ([]entity.Ad
 	return nil, errors.New("mock error")
 }
 
-// MockAccessLogRepository は infra.AccessLogRepository を模倣
-type MockAccessLogRepository struct{}
+// MockAccessLogRepository は entity.AccessLogRepository を模倣（保存と一覧以外はメモリの実装を使う）
+type MockAccessLogRepository struct {
+	*infra.MemoryAccessLogRepository
+}
+
+func NewMockAccessLogRepository() *MockAccessLogRepository {
+	return &MockAccessLogRepository{MemoryAccessLogRepository: infra.NewMemoryAccessLogRepository()}
+}
 
-func (m *MockAccessLogRepository) InsertAccessLog(postalCode string, createdAt time.Time) error {
-	if postalCode == "error" {
+func (m *MockAccessLogRepository) InsertAccessLog(entry entity.AccessLogEntry) error {
+	if entry.PostalCode == "error" {
 		return errors.New("mock save error")
 	}
 	return nil
 }
 
-func (m *MockAccessLogRepository) GetAccessLogs() ([]entity.AccessLog, error) {
+func (m *MockAccessLogRepository) GetAccessLogs(q entity.AccessLogQuery) ([]entity.AccessLog, error) {
 	return []entity.AccessLog{
 		{PostalCode: "1020073", RequestCount: 7},
 		{PostalCode: "1000001", RequestCount: 5},
@@ -54,7 +62,7 @@ func TestHandler_HandleAddress(t *testing.T) {
 
 	// モックをサービスにラップ
 	addressService := service.NewAddressService(&MockAddressRepository{}, cfg.ExternalAPI)
-	accessLogService := service.NewAccessLogService(&MockAccessLogRepository{})
+	accessLogService := service.NewAccessLogService(NewMockAccessLogRepository())
 
 	h := handler.NewHandler(addressService, accessLogService, cfg)
 
@@ -68,7 +76,7 @@ func TestHandler_HandleAddress(t *testing.T) {
 			name:           "正常な郵便番号",
 			postalCode:     "5016121",
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"postal_code":"5016121","hit_count":1,"common_address":"岐阜県岐阜市柳津町","tokyo_sta_distance":277.7}`,
+			expectedBody:   `{"postal_code":"5016121","hit_count":1,"address":"岐阜県岐阜市柳津町","tokyo_sta_distance":277.7}`,
 		},
 		{
 			name:           "郵便番号が空",
@@ -83,10 +91,10 @@ func TestHandler_HandleAddress(t *testing.T) {
 			expectedBody:   `{"error":"address not found"}`,
 		},
 		{
-			name:           "ログ保存エラー",
+			name:           "外部 API のエラー",
 			postalCode:     "error",
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"error":"failed to save access log"}`,
+			expectedBody:   `{"error":"mock error"}`,
 		},
 	}
 
@@ -104,13 +112,82 @@ func TestHandler_HandleAddress(t *testing.T) {
 	}
 }
 
+// FailingAccessLogRepository は保存に必ず失敗する entity.AccessLogRepository
+type FailingAccessLogRepository struct {
+	*infra.MemoryAccessLogRepository
+}
+
+func (m *FailingAccessLogRepository) InsertAccessLog(entry entity.AccessLogEntry) error {
+	return errors.New("mock save error")
+}
+
+func TestHandler_HandleAddressSaveFailure(t *testing.T) {
+	e := echo.New()
+	cfg := &config.Config{ExternalAPI: "https://example.com"}
+	addressService := service.NewAddressService(&MockAddressRepository{}, cfg.ExternalAPI)
+	accessLogService := service.NewAccessLogService(&FailingAccessLogRepository{infra.NewMemoryAccessLogRepository()})
+	h := handler.NewHandler(addressService, accessLogService, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/address?postal_code=5016121", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// アクセスログの保存に失敗しても検索結果を返す
+	err := h.HandleAddress(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"postal_code":"5016121","hit_count":1,"address":"岐阜県岐阜市柳津町","tokyo_sta_distance":277.7}`, rec.Body.String())
+}
+
+func TestHandler_HandleAddressRecordsOutcome(t *testing.T) {
+	e := echo.New()
+	cfg := &config.Config{ExternalAPI: "https://example.com"}
+	repo := infra.NewMemoryAccessLogRepository()
+	addressService := service.NewAddressService(&MockAddressRepository{}, cfg.ExternalAPI)
+	accessLogService := service.NewAccessLogService(repo)
+	h := handler.NewHandler(addressService, accessLogService, cfg)
+
+	for _, postalCode := range []string{"5016121", "9999999", "1234567"} {
+		req := httptest.NewRequest(http.MethodGet, "/address?postal_code="+postalCode, nil)
+		req.Header.Set("User-Agent", "test-agent")
+		req.Header.Set(echo.HeaderXRequestID, "req-"+postalCode)
+		req.RemoteAddr = "192.0.2.1:12345"
+		rec := httptest.NewRecorder()
+		assert.NoError(t, h.HandleAddress(e.NewContext(req, rec)))
+	}
+
+	var entries []entity.AccessLogEntry
+	err := repo.StreamAccessLogs(context.Background(), time.Time{}, time.Time{}, func(entry entity.AccessLogEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 3) {
+		// 検索の結果をステータス・件数・失敗の分類として記録する
+		assert.Equal(t, http.StatusOK, entries[0].Status)
+		assert.Equal(t, 1, entries[0].HitCount)
+		assert.Equal(t, entity.ErrorClassNone, entries[0].ErrorClass)
+		assert.Equal(t, http.StatusNotFound, entries[1].Status)
+		assert.Equal(t, entity.ErrorClassNotFound, entries[1].ErrorClass)
+		assert.Equal(t, http.StatusInternalServerError, entries[2].Status)
+		assert.Equal(t, entity.ErrorClassUpstream, entries[2].ErrorClass)
+
+		// リクエストの情報を記録する
+		assert.Equal(t, "192.0.2.1", entries[0].ClientIP)
+		assert.Equal(t, "test-agent", entries[0].UserAgent)
+		assert.Equal(t, "req-5016121", entries[0].RequestID)
+		assert.NotEmpty(t, entries[0].ClientID)
+		assert.Positive(t, entries[0].Latency)
+	}
+}
+
 func TestHandler_HandleAccessLogs(t *testing.T) {
 	e := echo.New()
 	cfg := &config.Config{Port: ":8080"}
 
 	// モックをサービスにラップ
 	addressService := service.NewAddressService(&MockAddressRepository{}, cfg.ExternalAPI)
-	accessLogService := service.NewAccessLogService(&MockAccessLogRepository{})
+	accessLogService := service.NewAccessLogService(NewMockAccessLogRepository())
 
 	h := handler.NewHandler(addressService, accessLogService, cfg)
 
@@ -123,10 +200,129 @@ func TestHandler_HandleAccessLogs(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 	expectedBody := `{
 		"access_logs": [
-			{"postal_code": "1020073", "request_count": 7},
-			{"postal_code": "1000001", "request_count": 5},
-			{"postal_code": "5300001", "request_count": 2}
+			{"postal_code": "1020073", "request_count": 7, "unique_clients": 0, "first_seen": "0001-01-01T00:00:00Z", "last_seen": "0001-01-01T00:00:00Z"},
+			{"postal_code": "1000001", "request_count": 5, "unique_clients": 0, "first_seen": "0001-01-01T00:00:00Z", "last_seen": "0001-01-01T00:00:00Z"},
+			{"postal_code": "5300001", "request_count": 2, "unique_clients": 0, "first_seen": "0001-01-01T00:00:00Z", "last_seen": "0001-01-01T00:00:00Z"}
 		]
 	}`
 	assert.JSONEq(t, expectedBody, rec.Body.String())
 }
+
+// MockReadiness は handler.ReadinessChecker を模倣
+type MockReadiness struct {
+	err error
+}
+
+func (m *MockReadiness) Ready() error {
+	return m.err
+}
+
+func TestHandler_HandleReadyz(t *testing.T) {
+	e := echo.New()
+	h := handler.NewHandler(nil, nil, &config.Config{})
+
+	tests := []struct {
+		name           string
+		readiness      handler.ReadinessChecker
+		expectedStatus int
+	}{
+		{name: "依存先なし", readiness: nil, expectedStatus: http.StatusOK},
+		{name: "DB に接続できる", readiness: &MockReadiness{}, expectedStatus: http.StatusOK},
+		{name: "DB に接続できない", readiness: &MockReadiness{err: errors.New("connection refused")}, expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.Readiness = tt.readiness
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := h.HandleReadyz(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestHandler_HandleReadyzWithSpool(t *testing.T) {
+	e := echo.New()
+	accessLogService := service.NewAccessLogService(NewMockAccessLogRepository())
+	accessLogService.Spool = &service.SpoolingAccessLogSink{}
+	h := handler.NewHandler(nil, accessLogService, &config.Config{})
+	h.Readiness = &MockReadiness{err: errors.New("connection refused")}
+
+	// スプールがあれば DB に接続できなくても ready（degraded）
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, h.HandleReadyz(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"degraded"}`, rec.Body.String())
+
+	h.Readiness = &MockReadiness{}
+	rec = httptest.NewRecorder()
+	assert.NoError(t, h.HandleReadyz(e.NewContext(req, rec)))
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
+
+func TestHandler_HandleAccessLogStats(t *testing.T) {
+	e := echo.New()
+	cfg := &config.Config{}
+	accessLogService := service.NewAccessLogService(NewMockAccessLogRepository())
+	accessLogService.Writer = service.NewAccessLogWriter(accessLogService.LogRepo, service.AccessLogWriterOptions{
+		BufferSize: 10, BatchSize: 10, FlushInterval: time.Hour, Overflow: service.OverflowDrop,
+	})
+	h := handler.NewHandler(nil, accessLogService, cfg)
+
+	assert.NoError(t, accessLogService.SaveAccessLog(entity.AccessLogEntry{PostalCode: "1000001"}))
+	assert.NoError(t, accessLogService.Close(context.Background()))
+
+	req := httptest.NewRequest(http.MethodGet, "/address/access_logs/stats", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := h.HandleAccessLogStats(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"writer": {"enqueued": 1, "dropped": 0, "flushed": 1, "failed": 0, "pending": 0}}`, rec.Body.String())
+}
+
+func TestHandler_HandleNearbyInvalidNumbers(t *testing.T) {
+	e := echo.New()
+	h := handler.NewHandler(nil, nil, &config.Config{})
+	h.NearbyService = service.NewNearbyService([]entity.PostalRecord{
+		{PostalCode: "1000001", Prefecture: "東京都", City: "千代田区", Town: "千代田", Lat: 35.6852, Lon: 139.7528},
+	})
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedBody string
+	}{
+		{name: "半径が NaN", query: "radius_km=NaN&lat=35.68&lon=139.75", expectedBody: `{"error":"radius_km must be a positive number"}`},
+		{name: "半径が無限大", query: "radius_km=Inf&lat=35.68&lon=139.75", expectedBody: `{"error":"radius_km must be a positive number"}`},
+		{name: "緯度が NaN", query: "radius_km=1&lat=NaN&lon=139.75", expectedBody: `{"error":"lat must be a number between -90 and 90"}`},
+		{name: "経度が NaN", query: "radius_km=1&lat=35.68&lon=nan", expectedBody: `{"error":"lon must be a number between -180 and 180"}`},
+		{name: "経度が負の無限大", query: "radius_km=1&lat=35.68&lon=-Infinity", expectedBody: `{"error":"lon must be a number between -180 and 180"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/address/nearby?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := h.HandleNearby(c)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+
+	// 有限の値は検索する
+	req := httptest.NewRequest(http.MethodGet, "/address/nearby?radius_km=1&lat=35.68&lon=139.75", nil)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, h.HandleNearby(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"postal_code":"1000001"`)
+}
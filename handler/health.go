@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/dkpcb/finatext_kadai_2/i18n"
+	"github.com/labstack/echo/v4"
+)
+
+// ReadinessChecker はリクエストを受け付けられる状態かを返す（受け付けられない場合はエラー）
+type ReadinessChecker interface {
+	Ready() error
+}
+
+// HandleHealthz はプロセスが動いていれば常に 200 を返す
+func (h *Handler) HandleHealthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// HandleReadyz は DB に接続できない間は 503 を返す
+// アクセスログのスプールがある場合は、検索と記録を続けられるため status を degraded にして 200 を返す
+// ログは ready でなくなったときと戻ったときだけ出す
+func (h *Handler) HandleReadyz(c echo.Context) error {
+	if h.Readiness != nil {
+		if err := h.Readiness.Ready(); err != nil {
+			if !h.notReady.Swap(true) {
+				log.Printf("Not ready: %v", err)
+			}
+			if h.AccessLogService != nil && h.AccessLogService.Spool != nil {
+				return c.JSON(http.StatusOK, map[string]string{"status": "degraded"})
+			}
+			return errorJSON(c, http.StatusServiceUnavailable, i18n.MsgDatabaseUnavailable)
+		}
+		if h.notReady.Swap(false) {
+			log.Printf("Ready again")
+		}
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"github.com/dkpcb/finatext_kadai_2/i18n"
+	"github.com/labstack/echo/v4"
+)
+
+// ?lang= または Accept-Language からリクエストの言語を決定
+func requestLang(c echo.Context) i18n.Lang {
+	return i18n.Negotiate(c.QueryParam("lang"), c.Request().Header.Get("Accept-Language"))
+}
+
+// リクエストの言語でローカライズしたエラーレスポンスを返す
+func errorJSON(c echo.Context, status int, key i18n.MessageKey, args ...interface{}) error {
+	return c.JSON(status, map[string]string{"error": i18n.Message(requestLang(c), key, args...)})
+}
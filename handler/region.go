@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dkpcb/finatext_kadai_2/i18n"
+	"github.com/dkpcb/finatext_kadai_2/util"
+	"github.com/labstack/echo/v4"
+)
+
+// HandlePrefectures は都道府県の一覧を返す
+func (h *Handler) HandlePrefectures(c echo.Context) error {
+	if h.RegionService == nil || !h.RegionService.Loaded() {
+		return errorJSON(c, http.StatusServiceUnavailable, i18n.MsgDatasetNotLoaded)
+	}
+
+	return respondWithETag(c, map[string]interface{}{
+		"prefectures": h.RegionService.Prefectures(),
+	})
+}
+
+// HandleCities は都道府県に属する市区町村の一覧を返す
+func (h *Handler) HandleCities(c echo.Context) error {
+	if h.RegionService == nil || !h.RegionService.Loaded() {
+		return errorJSON(c, http.StatusServiceUnavailable, i18n.MsgDatasetNotLoaded)
+	}
+
+	prefecture, cities, ok := h.RegionService.Cities(c.Param("code"))
+	if !ok {
+		return errorJSON(c, http.StatusNotFound, i18n.MsgPrefectureNotFound)
+	}
+
+	return respondWithETag(c, map[string]interface{}{
+		"prefecture": prefecture,
+		"cities":     cities,
+	})
+}
+
+// HandleTowns は市区町村に属する町域の一覧を返す
+func (h *Handler) HandleTowns(c echo.Context) error {
+	if h.RegionService == nil || !h.RegionService.Loaded() {
+		return errorJSON(c, http.StatusServiceUnavailable, i18n.MsgDatasetNotLoaded)
+	}
+
+	// /municipalities/:code と同じく 5 桁または検査数字付き 6 桁のコードを受け付ける
+	code, err := util.NormalizeJISCode(c.Param("code"))
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgInvalidMunicipalityCode)
+	}
+
+	city, towns, ok := h.RegionService.Towns(code)
+	if !ok {
+		return errorJSON(c, http.StatusNotFound, i18n.MsgCityNotFound)
+	}
+
+	return respondWithETag(c, map[string]interface{}{
+		"city":  city,
+		"towns": towns,
+	})
+}
+
+// HandleMunicipality は全国地方公共団体コードから市区町村名と郵便番号を返す
+func (h *Handler) HandleMunicipality(c echo.Context) error {
+	if h.RegionService == nil || !h.RegionService.Loaded() {
+		return errorJSON(c, http.StatusServiceUnavailable, i18n.MsgDatasetNotLoaded)
+	}
+
+	// 5 桁または検査数字付き 6 桁のコードを受け付ける
+	code, err := util.NormalizeJISCode(c.Param("code"))
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgInvalidMunicipalityCode)
+	}
+
+	municipality, ok := h.RegionService.Municipality(code)
+	if !ok {
+		return errorJSON(c, http.StatusNotFound, i18n.MsgMunicipalityNotFound)
+	}
+
+	return respondWithETag(c, municipality)
+}
@@ -0,0 +1,49 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dkpcb/finatext_kadai_2/config"
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/handler"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_HandleTowns(t *testing.T) {
+	e := echo.New()
+	h := handler.NewHandler(nil, nil, &config.Config{})
+	h.RegionService = service.NewRegionService([]entity.PostalRecord{
+		{PostalCode: "5016121", JISCode: "21201", Prefecture: "岐阜県", City: "岐阜市", Town: "柳津町"},
+	})
+
+	tests := []struct {
+		name           string
+		code           string
+		expectedStatus int
+	}{
+		{name: "5 桁", code: "21201", expectedStatus: http.StatusOK},
+		{name: "検査数字付き 6 桁", code: "212016", expectedStatus: http.StatusOK},
+		{name: "検査数字が誤っている", code: "212011", expectedStatus: http.StatusBadRequest},
+		{name: "桁数が誤っている", code: "2120", expectedStatus: http.StatusBadRequest},
+		{name: "数字でない", code: "2120a", expectedStatus: http.StatusBadRequest},
+		{name: "存在しない市区町村", code: "13101", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/cities/"+tt.code+"/towns", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("code")
+			c.SetParamValues(tt.code)
+
+			err := h.HandleTowns(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/i18n"
+	"github.com/labstack/echo/v4"
+)
+
+// ハートビートの間隔の既定値
+const defaultLookupStreamHeartbeat = 15 * time.Second
+
+// 住所検索の結果をライブストリームに配信
+func (h *Handler) publishLookup(entry entity.AccessLogEntry, address *entity.Address) {
+	if h.LookupHub == nil {
+		return
+	}
+	event := entity.LookupEvent{
+		PostalCode: entry.PostalCode,
+		Status:     entry.Status,
+		LatencyMs:  float64(entry.Latency.Microseconds()) / 1000,
+		RequestID:  entry.RequestID,
+		Time:       entry.CreatedAt,
+	}
+	if address != nil {
+		event.Address = address.CommonAddress
+	}
+	h.LookupHub.Publish(event)
+}
+
+// HandleLookupStream は完了した住所検索を Server-Sent Events で配信する
+// prefix を指定するとその前方一致の郵便番号のみを配信する。受信が追いつかない場合は evicted イベントを送って切断する
+func (h *Handler) HandleLookupStream(c echo.Context) error {
+	if h.LookupHub == nil {
+		return errorJSON(c, http.StatusServiceUnavailable, i18n.MsgLookupStreamUnavailable)
+	}
+	prefix := c.QueryParam("prefix")
+	if prefix != "" && (len(prefix) > 7 || !isDigits(prefix)) {
+		return errorJSON(c, http.StatusBadRequest, i18n.MsgPrefixInvalid)
+	}
+
+	sub := h.LookupHub.Subscribe(prefix)
+	defer sub.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	// リバースプロキシでバッファリングさせない
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	heartbeat := defaultLookupStreamHeartbeat
+	if h.Cfg != nil && h.Cfg.LookupStreamHeartbeat > 0 {
+		heartbeat = h.Cfg.LookupStreamHeartbeat
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	ctx := c.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Evicted() {
+					fmt.Fprint(res, "event: evicted\ndata: {}\n\n")
+					res.Flush()
+				}
+				return nil
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(res, "event: lookup\ndata: %s\n\n", data); err != nil {
+				return nil
+			}
+			res.Flush()
+		case <-ticker.C:
+			// 接続を維持するためのコメント行
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/config"
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/handler"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedRecorder は release が閉じられるまで本文の書き込みを止める httptest.ResponseRecorder
+// 配信中のハンドラーと並行して本文を読めるよう、書き込みと読み出しを排他する
+type gatedRecorder struct {
+	*httptest.ResponseRecorder
+	mu      sync.Mutex
+	once    sync.Once
+	entered chan struct{} // 最初の書き込みで閉じる
+	release chan struct{}
+}
+
+func newGatedRecorder() *gatedRecorder {
+	return &gatedRecorder{ResponseRecorder: httptest.NewRecorder(), entered: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (r *gatedRecorder) Write(b []byte) (int, error) {
+	r.once.Do(func() { close(r.entered) })
+	<-r.release
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ResponseRecorder.Write(b)
+}
+
+func (r *gatedRecorder) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ResponseRecorder.Flush()
+}
+
+func (r *gatedRecorder) body() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Body.String()
+}
+
+// ライブストリームを配信するハンドラーを開始し、終了を通知するチャネルを返す
+func startLookupStream(ctx context.Context, h *handler.Handler, query string, rec *gatedRecorder) <-chan error {
+	req := httptest.NewRequest(http.MethodGet, "/address/access_logs/stream?"+query, nil).WithContext(ctx)
+	c := echo.New().NewContext(req, rec)
+	done := make(chan error, 1)
+	go func() { done <- h.HandleLookupStream(c) }()
+	return done
+}
+
+func TestHandler_HandleLookupStream(t *testing.T) {
+	cfg := &config.Config{ExternalAPI: "https://example.com", LookupStreamHeartbeat: time.Hour}
+	addressService := service.NewAddressService(&MockAddressRepository{}, cfg.ExternalAPI)
+	h := handler.NewHandler(addressService, service.NewAccessLogService(NewMockAccessLogRepository()), cfg)
+	hub := service.NewLookupHub(10)
+	h.LookupHub = hub
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rec := newGatedRecorder()
+	close(rec.release)
+	done := startLookupStream(ctx, h, "prefix=501", rec)
+	require.Eventually(t, func() bool { return hub.Subscribers() == 1 }, time.Second, time.Millisecond)
+
+	// prefix に一致する検索結果だけを配信する
+	e := echo.New()
+	for _, postalCode := range []string{"9999999", "5016121"} {
+		req := httptest.NewRequest(http.MethodGet, "/address?postal_code="+postalCode, nil)
+		req.Header.Set(echo.HeaderXRequestID, "req-"+postalCode)
+		require.NoError(t, h.HandleAddress(e.NewContext(req, httptest.NewRecorder())))
+	}
+	require.Eventually(t, func() bool { return strings.Contains(rec.body(), "\n\n") }, time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.Equal(t, "text/event-stream", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "no-cache", rec.Header().Get(echo.HeaderCacheControl))
+	body := rec.Body.String()
+	require.True(t, strings.HasPrefix(body, "event: lookup\ndata: "), body)
+	require.True(t, strings.HasSuffix(body, "\n\n"), body)
+	assert.Equal(t, 1, strings.Count(body, "event: "))
+
+	var event entity.LookupEvent
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSuffix(strings.TrimPrefix(body, "event: lookup\ndata: "), "\n\n")), &event))
+	assert.Equal(t, "5016121", event.PostalCode)
+	assert.Equal(t, http.StatusOK, event.Status)
+	assert.Equal(t, "岐阜県岐阜市柳津町", event.Address)
+	assert.Equal(t, "req-5016121", event.RequestID)
+	assert.Positive(t, event.LatencyMs)
+	assert.False(t, event.Time.IsZero())
+}
+
+func TestHandler_HandleLookupStreamEvictsSlowClient(t *testing.T) {
+	h := handler.NewHandler(nil, nil, &config.Config{LookupStreamHeartbeat: time.Hour})
+	hub := service.NewLookupHub(1)
+	h.LookupHub = hub
+
+	rec := newGatedRecorder()
+	done := startLookupStream(context.Background(), h, "", rec)
+	require.Eventually(t, func() bool { return hub.Subscribers() == 1 }, time.Second, time.Millisecond)
+
+	// 1 件目の書き込みが止まっている間にバッファ（1 件）を超えて配信する
+	hub.Publish(entity.LookupEvent{PostalCode: "1000001", Status: http.StatusOK})
+	<-rec.entered
+	hub.Publish(entity.LookupEvent{PostalCode: "1000002", Status: http.StatusOK})
+	hub.Publish(entity.LookupEvent{PostalCode: "1000003", Status: http.StatusOK})
+	assert.Equal(t, 0, hub.Subscribers())
+
+	// 受け取り済みの分を送ってから evicted イベントを送り、切断する
+	close(rec.release)
+	require.NoError(t, <-done)
+	assert.Equal(t, strings.Join([]string{
+		`event: lookup`,
+		`data: {"postal_code":"1000001","status":200,"latency_ms":0,"time":"0001-01-01T00:00:00Z"}`,
+		``,
+		`event: lookup`,
+		`data: {"postal_code":"1000002","status":200,"latency_ms":0,"time":"0001-01-01T00:00:00Z"}`,
+		``,
+		`event: evicted`,
+		`data: {}`,
+		``,
+		``,
+	}, "\n"), rec.Body.String())
+}
+
+func TestHandler_HandleLookupStreamErrors(t *testing.T) {
+	h := handler.NewHandler(nil, nil, &config.Config{})
+
+	tests := []struct {
+		name           string
+		hub            *service.LookupHub
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "ライブストリームなし", hub: nil, expectedStatus: http.StatusServiceUnavailable, expectedBody: `{"error":"lookup stream is not available"}`},
+		{name: "prefix が数字でない", hub: service.NewLookupHub(1), query: "prefix=10a", expectedStatus: http.StatusBadRequest, expectedBody: `{"error":"prefix must be 1 to 7 digits"}`},
+		{name: "prefix が長すぎる", hub: service.NewLookupHub(1), query: "prefix=10000011", expectedStatus: http.StatusBadRequest, expectedBody: `{"error":"prefix must be 1 to 7 digits"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.LookupHub = tt.hub
+			req := httptest.NewRequest(http.MethodGet, "/address/access_logs/stream?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			err := h.HandleLookupStream(c)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dkpcb/finatext_kadai_2/util"
+)
+
+// Lang はレスポンスの言語
+type Lang string
+
+const (
+	Japanese Lang = "ja"
+	English  Lang = "en"
+)
+
+// 対応している言語
+var supported = map[Lang]bool{Japanese: true, English: true}
+
+// ?lang= と Accept-Language から言語を決定（指定がない場合は空文字）
+func Negotiate(langParam, acceptLanguage string) Lang {
+	if lang, ok := normalize(langParam); ok {
+		return lang
+	}
+
+	// q 値の高い順に対応している言語を探す
+	for _, tag := range util.ParseQualityList(acceptLanguage) {
+		if lang, ok := normalize(tag); ok {
+			return lang
+		}
+	}
+	return ""
+}
+
+// "ja-JP" のような言語タグを対応している言語に変換
+func normalize(tag string) (Lang, bool) {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	lang := Lang(primary)
+	return lang, supported[lang]
+}
+
+// メッセージカタログから指定した言語のメッセージを返す（未対応の言語は英語）
+func Message(lang Lang, key MessageKey, args ...interface{}) string {
+	format, ok := catalog[lang][key]
+	if !ok {
+		format, ok = catalog[English][key]
+	}
+	if !ok {
+		return string(key)
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
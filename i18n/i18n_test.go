@@ -0,0 +1,66 @@
+package i18n
+
+import (
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		langParam      string
+		acceptLanguage string
+		want           Lang
+	}{
+		{"指定なし", "", "", ""},
+		{"lang パラメータを優先", "en", "ja-JP", English},
+		{"地域付きの言語タグ", "", "ja-JP,ja;q=0.9", Japanese},
+		{"q 値の高い言語を選択", "", "ja;q=0.5, en-US;q=0.8", English},
+		{"未対応の言語は読み飛ばす", "", "fr-FR, en;q=0.3", English},
+		{"未対応の言語のみ", "fr", "de", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.langParam, tt.acceptLanguage); got != tt.want {
+				t.Errorf("Negotiate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if got := Message("", MsgPostalCodeRequired); got != "postal_code is required" {
+		t.Errorf("Message() = %q, want English fallback", got)
+	}
+	if got := Message(Japanese, MsgAddressNotFound); got != "住所が見つかりません" {
+		t.Errorf("Message() = %q", got)
+	}
+	if got := Message(English, MsgLimitOutOfRange, 500); got != "limit must be between 1 and 500" {
+		t.Errorf("Message() = %q", got)
+	}
+}
+
+func TestFormatAddress(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lang                   Lang
+		prefecture, city, town string
+		want                   string
+	}{
+		{"日本語", Japanese, "岐阜県", "岐阜市", "柳津町", "岐阜県岐阜市柳津町"},
+		{"言語指定なし", "", "岐阜県", "岐阜市", "柳津町", "岐阜県岐阜市柳津町"},
+		{"英語", English, "岐阜県", "岐阜市", "", "Gifu Prefecture, Gifu City"},
+		{"英語（町域は翻訳しない）", English, "岐阜県", "岐阜市", "柳津町", "Gifu Prefecture, Gifu City, 柳津町"},
+		{"東京都の特別区", English, "東京都", "千代田区", "", "Tokyo, Chiyoda City"},
+		{"政令指定都市の区", English, "大阪府", "大阪市北区", "", "Osaka Prefecture, Osaka City, Kita Ward"},
+		{"対応表にない市", English, "岐阜県", "羽島市", "", "Gifu Prefecture, 羽島市"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatAddress(tt.lang, tt.prefecture, tt.city, tt.town); got != tt.want {
+				t.Errorf("FormatAddress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package i18n
+
+// MessageKey はメッセージカタログのキー
+type MessageKey string
+
+const (
+	MsgPostalCodeRequired         MessageKey = "postal_code_required"
+	MsgAddressNotFound            MessageKey = "address_not_found"
+	MsgUnsupportedFormat          MessageKey = "unsupported_format"
+	MsgInvalidRequestBody         MessageKey = "invalid_request_body"
+	MsgOriginsDestinationsMissing MessageKey = "origins_destinations_required"
+	MsgMatrixTooLarge             MessageKey = "matrix_too_large"
+	MsgDatasetNotLoaded           MessageKey = "dataset_not_loaded"
+	MsgRadiusInvalid              MessageKey = "radius_invalid"
+	MsgRadiusTooLarge             MessageKey = "radius_too_large"
+	MsgLimitOutOfRange            MessageKey = "limit_out_of_range"
+	MsgOffsetInvalid              MessageKey = "offset_invalid"
+	MsgLocationRequired           MessageKey = "location_required"
+	MsgLatInvalid                 MessageKey = "lat_invalid"
+	MsgLonInvalid                 MessageKey = "lon_invalid"
+	MsgPrefectureNotFound         MessageKey = "prefecture_not_found"
+	MsgCityNotFound               MessageKey = "city_not_found"
+	MsgInvalidMunicipalityCode    MessageKey = "invalid_municipality_code"
+	MsgMunicipalityNotFound       MessageKey = "municipality_not_found"
+	MsgSinceInvalid               MessageKey = "since_invalid"
+	MsgPostalCodeSuperseded       MessageKey = "postal_code_superseded"
+	MsgFromInvalid                MessageKey = "from_invalid"
+	MsgToInvalid                  MessageKey = "to_invalid"
+	MsgTimeRangeInvalid           MessageKey = "time_range_invalid"
+	MsgPrefixInvalid              MessageKey = "prefix_invalid"
+	MsgSortInvalid                MessageKey = "sort_invalid"
+	MsgOrderInvalid               MessageKey = "order_invalid"
+	MsgCursorInvalid              MessageKey = "cursor_invalid"
+	MsgIntervalInvalid            MessageKey = "interval_invalid"
+	MsgTooManyBuckets             MessageKey = "too_many_buckets"
+	MsgExportFormatInvalid        MessageKey = "export_format_invalid"
+	MsgRegionLevelInvalid         MessageKey = "region_level_invalid"
+	MsgWindowInvalid              MessageKey = "window_invalid"
+	MsgDatabaseUnavailable        MessageKey = "database_unavailable"
+	MsgFromBeforeRetention        MessageKey = "from_before_retention"
+	MsgPostalCodeInvalid          MessageKey = "postal_code_invalid"
+	MsgLookupStreamUnavailable    MessageKey = "lookup_stream_unavailable"
+	MsgTrendingUnavailable        MessageKey = "trending_unavailable"
+)
+
+// 言語ごとのメッセージカタログ（書式は fmt.Sprintf 形式）
+var catalog = map[Lang]map[MessageKey]string{
+	English: {
+		MsgPostalCodeRequired:         "postal_code is required",
+		MsgAddressNotFound:            "address not found",
+		MsgUnsupportedFormat:          "unsupported response format",
+		MsgInvalidRequestBody:         "invalid request body",
+		MsgOriginsDestinationsMissing: "origins and destinations are required",
+		MsgMatrixTooLarge:             "matrix too large: at most %d cells are allowed",
+		MsgDatasetNotLoaded:           "postal dataset is not loaded",
+		MsgRadiusInvalid:              "radius_km must be a positive number",
+		MsgRadiusTooLarge:             "radius_km must not exceed %v",
+		MsgLimitOutOfRange:            "limit must be between 1 and %d",
+		MsgOffsetInvalid:              "offset must be a non-negative integer",
+		MsgLocationRequired:           "postal_code or lat and lon are required",
+		MsgLatInvalid:                 "lat must be a number between -90 and 90",
+		MsgLonInvalid:                 "lon must be a number between -180 and 180",
+		MsgPrefectureNotFound:         "prefecture not found",
+		MsgCityNotFound:               "city not found",
+		MsgInvalidMunicipalityCode:    "municipality code must be 5 digits or 6 digits with a valid check digit",
+		MsgMunicipalityNotFound:       "municipality not found",
+		MsgSinceInvalid:               "since must be a month in YYYY-MM format",
+		MsgPostalCodeSuperseded:       "postal code %s was retired in %s; use %s instead",
+		MsgFromInvalid:                "from must be an RFC 3339 date-time or a YYYY-MM-DD date",
+		MsgToInvalid:                  "to must be an RFC 3339 date-time or a YYYY-MM-DD date",
+		MsgTimeRangeInvalid:           "from must be before to",
+		MsgPrefixInvalid:              "prefix must be 1 to 7 digits",
+		MsgSortInvalid:                "sort must be one of count, postal_code or last_seen",
+		MsgOrderInvalid:               "order must be asc or desc",
+		MsgCursorInvalid:              "cursor is invalid or does not match the sort order",
+		MsgIntervalInvalid:            "interval must be one of hour, day or week",
+		MsgTooManyBuckets:             "time range too large: at most %d buckets are allowed",
+		MsgExportFormatInvalid:        "format must be csv or ndjson",
+		MsgRegionLevelInvalid:         "level must be prefecture or city",
+		MsgWindowInvalid:              "window must be a positive duration up to %s (e.g. 15m)",
+		MsgDatabaseUnavailable:        "database is unavailable",
+		MsgFromBeforeRetention:        "from must not be earlier than %s; older access logs are kept only as daily totals",
+		MsgPostalCodeInvalid:          "postal_code must be 7 digits",
+		MsgLookupStreamUnavailable:    "lookup stream is not available",
+		MsgTrendingUnavailable:        "trending is not available",
+	},
+	Japanese: {
+		MsgPostalCodeRequired:         "郵便番号（postal_code）は必須です",
+		MsgAddressNotFound:            "住所が見つかりません",
+		MsgUnsupportedFormat:          "対応していないレスポンス形式です",
+		MsgInvalidRequestBody:         "リクエストボディが不正です",
+		MsgOriginsDestinationsMissing: "出発地（origins）と目的地（destinations）は必須です",
+		MsgMatrixTooLarge:             "距離行列が大きすぎます（最大 %d セル）",
+		MsgDatasetNotLoaded:           "郵便番号データセットが読み込まれていません",
+		MsgRadiusInvalid:              "radius_km は正の数で指定してください",
+		MsgRadiusTooLarge:             "radius_km は %v 以下で指定してください",
+		MsgLimitOutOfRange:            "limit は 1 以上 %d 以下で指定してください",
+		MsgOffsetInvalid:              "offset は 0 以上の整数で指定してください",
+		MsgLocationRequired:           "postal_code または lat と lon を指定してください",
+		MsgLatInvalid:                 "lat は -90 以上 90 以下の数値で指定してください",
+		MsgLonInvalid:                 "lon は -180 以上 180 以下の数値で指定してください",
+		MsgPrefectureNotFound:         "都道府県が見つかりません",
+		MsgCityNotFound:               "市区町村が見つかりません",
+		MsgInvalidMunicipalityCode:    "市区町村コードは 5 桁、または正しい検査数字付きの 6 桁で指定してください",
+		MsgMunicipalityNotFound:       "市区町村が見つかりません",
+		MsgSinceInvalid:               "since は YYYY-MM 形式の年月で指定してください",
+		MsgPostalCodeSuperseded:       "郵便番号 %s は %s に廃止されました。%s を使用してください",
+		MsgFromInvalid:                "from は RFC 3339 形式の日時または YYYY-MM-DD 形式の日付で指定してください",
+		MsgToInvalid:                  "to は RFC 3339 形式の日時または YYYY-MM-DD 形式の日付で指定してください",
+		MsgTimeRangeInvalid:           "from は to より前の日時で指定してください",
+		MsgPrefixInvalid:              "prefix は 1 桁から 7 桁の数字で指定してください",
+		MsgSortInvalid:                "sort は count、postal_code、last_seen のいずれかで指定してください",
+		MsgOrderInvalid:               "order は asc または desc で指定してください",
+		MsgCursorInvalid:              "cursor が不正か、並び順と一致しません",
+		MsgIntervalInvalid:            "interval は hour、day、week のいずれかで指定してください",
+		MsgTooManyBuckets:             "期間が長すぎます（最大 %d 区間）",
+		MsgExportFormatInvalid:        "format は csv または ndjson で指定してください",
+		MsgRegionLevelInvalid:         "level は prefecture または city で指定してください",
+		MsgWindowInvalid:              "window は %s 以下の正の期間で指定してください（例: 15m）",
+		MsgDatabaseUnavailable:        "データベースに接続できません",
+		MsgFromBeforeRetention:        "from は %s 以降で指定してください（それより前のアクセスログは日次の集計のみ残っています）",
+		MsgPostalCodeInvalid:          "postal_code は 7 桁の数字で指定してください",
+		MsgLookupStreamUnavailable:    "住所検索のライブストリームは利用できません",
+		MsgTrendingUnavailable:        "アクセスの多い郵便番号の集計は利用できません",
+	},
+}
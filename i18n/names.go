@@ -0,0 +1,199 @@
+package i18n
+
+import (
+	"strings"
+)
+
+// 都道府県名の英語表記
+var prefectureNames = map[string]string{
+	"北海道":  "Hokkaido",
+	"青森県":  "Aomori Prefecture",
+	"岩手県":  "Iwate Prefecture",
+	"宮城県":  "Miyagi Prefecture",
+	"秋田県":  "Akita Prefecture",
+	"山形県":  "Yamagata Prefecture",
+	"福島県":  "Fukushima Prefecture",
+	"茨城県":  "Ibaraki Prefecture",
+	"栃木県":  "Tochigi Prefecture",
+	"群馬県":  "Gunma Prefecture",
+	"埼玉県":  "Saitama Prefecture",
+	"千葉県":  "Chiba Prefecture",
+	"東京都":  "Tokyo",
+	"神奈川県": "Kanagawa Prefecture",
+	"新潟県":  "Niigata Prefecture",
+	"富山県":  "Toyama Prefecture",
+	"石川県":  "Ishikawa Prefecture",
+	"福井県":  "Fukui Prefecture",
+	"山梨県":  "Yamanashi Prefecture",
+	"長野県":  "Nagano Prefecture",
+	"岐阜県":  "Gifu Prefecture",
+	"静岡県":  "Shizuoka Prefecture",
+	"愛知県":  "Aichi Prefecture",
+	"三重県":  "Mie Prefecture",
+	"滋賀県":  "Shiga Prefecture",
+	"京都府":  "Kyoto Prefecture",
+	"大阪府":  "Osaka Prefecture",
+	"兵庫県":  "Hyogo Prefecture",
+	"奈良県":  "Nara Prefecture",
+	"和歌山県": "Wakayama Prefecture",
+	"鳥取県":  "Tottori Prefecture",
+	"島根県":  "Shimane Prefecture",
+	"岡山県":  "Okayama Prefecture",
+	"広島県":  "Hiroshima Prefecture",
+	"山口県":  "Yamaguchi Prefecture",
+	"徳島県":  "Tokushima Prefecture",
+	"香川県":  "Kagawa Prefecture",
+	"愛媛県":  "Ehime Prefecture",
+	"高知県":  "Kochi Prefecture",
+	"福岡県":  "Fukuoka Prefecture",
+	"佐賀県":  "Saga Prefecture",
+	"長崎県":  "Nagasaki Prefecture",
+	"熊本県":  "Kumamoto Prefecture",
+	"大分県":  "Oita Prefecture",
+	"宮崎県":  "Miyazaki Prefecture",
+	"鹿児島県": "Kagoshima Prefecture",
+	"沖縄県":  "Okinawa Prefecture",
+}
+
+// 市名の英語表記（県庁所在地と政令指定都市）
+var cityNames = map[string]string{
+	"札幌市":   "Sapporo City",
+	"青森市":   "Aomori City",
+	"盛岡市":   "Morioka City",
+	"仙台市":   "Sendai City",
+	"秋田市":   "Akita City",
+	"山形市":   "Yamagata City",
+	"福島市":   "Fukushima City",
+	"水戸市":   "Mito City",
+	"宇都宮市":  "Utsunomiya City",
+	"前橋市":   "Maebashi City",
+	"さいたま市": "Saitama City",
+	"千葉市":   "Chiba City",
+	"横浜市":   "Yokohama City",
+	"川崎市":   "Kawasaki City",
+	"相模原市":  "Sagamihara City",
+	"新潟市":   "Niigata City",
+	"富山市":   "Toyama City",
+	"金沢市":   "Kanazawa City",
+	"福井市":   "Fukui City",
+	"甲府市":   "Kofu City",
+	"長野市":   "Nagano City",
+	"岐阜市":   "Gifu City",
+	"静岡市":   "Shizuoka City",
+	"浜松市":   "Hamamatsu City",
+	"名古屋市":  "Nagoya City",
+	"津市":    "Tsu City",
+	"大津市":   "Otsu City",
+	"京都市":   "Kyoto City",
+	"大阪市":   "Osaka City",
+	"堺市":    "Sakai City",
+	"神戸市":   "Kobe City",
+	"奈良市":   "Nara City",
+	"和歌山市":  "Wakayama City",
+	"鳥取市":   "Tottori City",
+	"松江市":   "Matsue City",
+	"岡山市":   "Okayama City",
+	"広島市":   "Hiroshima City",
+	"山口市":   "Yamaguchi City",
+	"徳島市":   "Tokushima City",
+	"高松市":   "Takamatsu City",
+	"松山市":   "Matsuyama City",
+	"高知市":   "Kochi City",
+	"北九州市":  "Kitakyushu City",
+	"福岡市":   "Fukuoka City",
+	"佐賀市":   "Saga City",
+	"長崎市":   "Nagasaki City",
+	"熊本市":   "Kumamoto City",
+	"大分市":   "Oita City",
+	"宮崎市":   "Miyazaki City",
+	"鹿児島市":  "Kagoshima City",
+	"那覇市":   "Naha City",
+}
+
+// 東京都の特別区の英語表記
+var tokyoWardNames = map[string]string{
+	"千代田区": "Chiyoda City",
+	"中央区":  "Chuo City",
+	"港区":   "Minato City",
+	"新宿区":  "Shinjuku City",
+	"文京区":  "Bunkyo City",
+	"台東区":  "Taito City",
+	"墨田区":  "Sumida City",
+	"江東区":  "Koto City",
+	"品川区":  "Shinagawa City",
+	"目黒区":  "Meguro City",
+	"大田区":  "Ota City",
+	"世田谷区": "Setagaya City",
+	"渋谷区":  "Shibuya City",
+	"中野区":  "Nakano City",
+	"杉並区":  "Suginami City",
+	"豊島区":  "Toshima City",
+	"北区":   "Kita City",
+	"荒川区":  "Arakawa City",
+	"板橋区":  "Itabashi City",
+	"練馬区":  "Nerima City",
+	"足立区":  "Adachi City",
+	"葛飾区":  "Katsushika City",
+	"江戸川区": "Edogawa City",
+}
+
+// 政令指定都市の行政区の英語表記（方角などの共通する区名のみ）
+var wardNames = map[string]string{
+	"北区":  "Kita Ward",
+	"南区":  "Minami Ward",
+	"東区":  "Higashi Ward",
+	"西区":  "Nishi Ward",
+	"中区":  "Naka Ward",
+	"中央区": "Chuo Ward",
+	"港区":  "Minato Ward",
+}
+
+// 都道府県名を指定した言語で返す（対応表にない場合はそのまま）
+func PrefectureName(lang Lang, prefecture string) string {
+	if lang != English {
+		return prefecture
+	}
+	if name, ok := prefectureNames[prefecture]; ok {
+		return name
+	}
+	return prefecture
+}
+
+// 市区町村名を指定した言語で返す（対応表にない場合はそのまま）
+func CityName(lang Lang, prefecture, city string) string {
+	if lang != English {
+		return city
+	}
+	if name, ok := cityNames[city]; ok {
+		return name
+	}
+	if prefecture == "東京都" {
+		if name, ok := tokyoWardNames[city]; ok {
+			return name
+		}
+	}
+
+	// 政令指定都市の区（例: 大阪市北区）は「市, 区」の順に並べる
+	if i := strings.Index(city, "市"); i > 0 {
+		cityPart, wardPart := city[:i+len("市")], city[i+len("市"):]
+		cityName, cityOK := cityNames[cityPart]
+		wardName, wardOK := wardNames[wardPart]
+		if cityOK && wardOK {
+			return cityName + ", " + wardName
+		}
+	}
+	return city
+}
+
+// 都道府県・市区町村・町域から住所の表記を組み立てる
+func FormatAddress(lang Lang, prefecture, city, town string) string {
+	if lang != English {
+		return prefecture + city + town
+	}
+
+	parts := []string{PrefectureName(lang, prefecture), CityName(lang, prefecture, city)}
+	if town != "" {
+		parts = append(parts, town)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,352 @@
+package infra
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/util"
+)
+
+// MemoryAccessLogRepository はメモリに保存する entity.AccessLogRepository（再起動すると消える）
+type MemoryAccessLogRepository struct {
+	mu            sync.RWMutex
+	entries       []entity.AccessLogEntry // 保存順
+	ids           map[string]struct{}
+	rollups       map[uniqueClientKey]*entity.AccessLog
+	counters      map[string]*entity.AccessLog
+	uniqueClients map[uniqueClientKey]*util.HyperLogLog
+}
+
+// 新しい MemoryAccessLogRepository を作成
+func NewMemoryAccessLogRepository() *MemoryAccessLogRepository {
+	return &MemoryAccessLogRepository{
+		ids:           make(map[string]struct{}),
+		rollups:       make(map[uniqueClientKey]*entity.AccessLog),
+		counters:      make(map[string]*entity.AccessLog),
+		uniqueClients: make(map[uniqueClientKey]*util.HyperLogLog),
+	}
+}
+
+// 住所検索の結果を含むアクセスログを保存
+func (r *MemoryAccessLogRepository) InsertAccessLog(entry entity.AccessLogEntry) error {
+	return r.InsertAccessLogs([]entity.AccessLogEntry{entry})
+}
+
+// 複数のアクセスログを保存し、郵便番号ごとのカウンターとクライアントのスケッチに反映する
+// 同じ ID のアクセスログが保存済みの場合は保存も加算もしない
+func (r *MemoryAccessLogRepository) InsertAccessLogs(entries []entity.AccessLogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, entry := range entries {
+		if entry.ID != "" {
+			if _, ok := r.ids[entry.ID]; ok {
+				continue
+			}
+			r.ids[entry.ID] = struct{}{}
+		}
+		entry.CreatedAt = entry.CreatedAt.UTC()
+		entry.ClientIP = truncate(entry.ClientIP, accessLogClientIPMaxLength)
+		entry.UserAgent = truncate(entry.UserAgent, accessLogUserAgentMaxLength)
+		entry.RequestID = truncate(entry.RequestID, accessLogRequestIDMaxLength)
+		entry.ErrorClass = truncate(entry.ErrorClass, accessLogErrorClassMaxLength)
+		r.entries = append(r.entries, entry)
+
+		addToAccessLog(r.counters, entry.PostalCode, 1, entry.CreatedAt, entry.CreatedAt)
+		if entry.ClientID != "" {
+			key := uniqueClientKey{day: entry.CreatedAt.Format("2006-01-02"), postalCode: entry.PostalCode}
+			if r.uniqueClients[key] == nil {
+				r.uniqueClients[key] = util.NewHyperLogLog()
+			}
+			r.uniqueClients[key].Add(entry.ClientID)
+		}
+	}
+	return nil
+}
+
+// 条件に一致するアクセスログを郵便番号ごとに集計して返す
+// 期間の指定がない場合はカウンターを、ある場合は生のアクセスログと日次の集約を合算して返す
+func (r *MemoryAccessLogRepository) GetAccessLogs(q entity.AccessLogQuery) ([]entity.AccessLog, error) {
+	r.mu.RLock()
+	var logs []entity.AccessLog
+	if q.From.IsZero() && q.To.IsZero() {
+		for _, c := range r.counters {
+			if strings.HasPrefix(c.PostalCode, q.Prefix) {
+				logs = append(logs, *c)
+			}
+		}
+	} else {
+		logs = r.aggregate(q.From, q.To, q.Prefix)
+	}
+	r.mu.RUnlock()
+
+	// 並び替えのキーを比較する（同じ値の場合は郵便番号で比べる）
+	compare := func(a, b entity.AccessLog) int {
+		switch q.Sort {
+		case entity.AccessLogSortLastSeen:
+			if c := a.LastSeen.Compare(b.LastSeen); c != 0 {
+				return c
+			}
+		case entity.AccessLogSortPostalCode:
+		default:
+			if a.RequestCount != b.RequestCount {
+				if a.RequestCount < b.RequestCount {
+					return -1
+				}
+				return 1
+			}
+		}
+		return strings.Compare(a.PostalCode, b.PostalCode)
+	}
+	direction := -1
+	if q.Order == entity.OrderAsc {
+		direction = 1
+	}
+	sort.Slice(logs, func(i, j int) bool { return compare(logs[i], logs[j])*direction < 0 })
+
+	// 前のページの最後の行より後ろの行だけを返す
+	if q.After != nil {
+		after := entity.AccessLog{PostalCode: q.After.PostalCode, RequestCount: q.After.RequestCount, LastSeen: q.After.LastSeen}
+		i := sort.Search(len(logs), func(i int) bool { return compare(logs[i], after)*direction > 0 })
+		logs = logs[i:]
+	}
+	if q.Limit > 0 && len(logs) > q.Limit {
+		logs = logs[:q.Limit]
+	}
+	return logs, nil
+}
+
+// 生のアクセスログと日次の集約を郵便番号ごとに合算する（mu を保持して呼び出す）
+func (r *MemoryAccessLogRepository) aggregate(from, to time.Time, prefix string) []entity.AccessLog {
+	totals := make(map[string]*entity.AccessLog)
+	for _, entry := range r.entries {
+		if inRange(entry.CreatedAt, from, to) && strings.HasPrefix(entry.PostalCode, prefix) {
+			addToAccessLog(totals, entry.PostalCode, 1, entry.CreatedAt, entry.CreatedAt)
+		}
+	}
+	// 日次の集約は期間に丸ごと含まれる日だけを含める
+	firstDay, endDay := wholeDays(from, to)
+	for key, rollup := range r.rollups {
+		if (!from.IsZero() && key.day < firstDay) || (!to.IsZero() && key.day >= endDay) {
+			continue
+		}
+		if strings.HasPrefix(rollup.PostalCode, prefix) {
+			addToAccessLog(totals, rollup.PostalCode, rollup.RequestCount, rollup.FirstSeen, rollup.LastSeen)
+		}
+	}
+
+	logs := make([]entity.AccessLog, 0, len(totals))
+	for _, total := range totals {
+		logs = append(logs, *total)
+	}
+	return logs
+}
+
+// 郵便番号ごとのカウンターをすべて返す
+func (r *MemoryAccessLogRepository) ListCounters() ([]entity.AccessLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	logs := make([]entity.AccessLog, 0, len(r.counters))
+	for _, c := range r.counters {
+		logs = append(logs, *c)
+	}
+	sortByPostalCode(logs)
+	return logs, nil
+}
+
+// 生のアクセスログと日次の集約から郵便番号ごとの件数を計算して返す
+func (r *MemoryAccessLogRepository) ComputeCounters() ([]entity.AccessLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	logs := r.aggregate(time.Time{}, time.Time{}, "")
+	sortByPostalCode(logs)
+	return logs, nil
+}
+
+// カウンターを生のアクセスログと日次の集約から作り直す
+func (r *MemoryAccessLogRepository) RebuildCounters() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters = make(map[string]*entity.AccessLog)
+	for _, log := range r.aggregate(time.Time{}, time.Time{}, "") {
+		log := log
+		r.counters[log.PostalCode] = &log
+	}
+	return nil
+}
+
+// メモリのカウンターは常に最新のため何もしない
+func (r *MemoryAccessLogRepository) InitializeCounters() error {
+	return nil
+}
+
+// 期間内の生のアクセスログを保存順に 1 件ずつ fn に渡す
+func (r *MemoryAccessLogRepository) StreamAccessLogs(ctx context.Context, from, to time.Time, fn func(entity.AccessLogEntry) error) error {
+	// fn の中で保存できるよう、ロックを保持したまま fn を呼び出さない
+	r.mu.RLock()
+	var entries []entity.AccessLogEntry
+	for _, entry := range r.entries {
+		if inRange(entry.CreatedAt, from, to) {
+			entries = append(entries, entry)
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 期間内の生のアクセスログを地域コードごとに集計して返す
+func (r *MemoryAccessLogRepository) CountAccessLogsByRegion(level string, from, to time.Time) ([]entity.AccessLogRegionCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	totals := make(map[string]int)
+	for _, entry := range r.entries {
+		if !inRange(entry.CreatedAt, from, to) {
+			continue
+		}
+		code := entry.PrefectureCode
+		if level == entity.RegionLevelCity {
+			code = entry.CityCode
+		}
+		totals[code]++
+	}
+
+	counts := make([]entity.AccessLogRegionCount, 0, len(totals))
+	for code, n := range totals {
+		counts = append(counts, entity.AccessLogRegionCount{Code: code, RequestCount: n})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Code < counts[j].Code })
+	return counts, nil
+}
+
+// 期間内のアクセス数を 1 時間ごと（UTC）に集計して返す
+func (r *MemoryAccessLogRepository) CountAccessLogsByHour(postalCode string, from, to time.Time) ([]entity.AccessLogBucket, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	totals := make(map[time.Time]int)
+	for _, entry := range r.entries {
+		if inRange(entry.CreatedAt, from, to) && (postalCode == "" || entry.PostalCode == postalCode) {
+			totals[entry.CreatedAt.Truncate(time.Hour)]++
+		}
+	}
+
+	buckets := make([]entity.AccessLogBucket, 0, len(totals))
+	for start, n := range totals {
+		buckets = append(buckets, entity.AccessLogBucket{Start: start, Count: n})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+	return buckets, nil
+}
+
+// 郵便番号ごとに、期間と重なる日のスケッチを合わせてクライアントの種類数を推定する
+func (r *MemoryAccessLogRepository) CountUniqueClients(postalCodes []string, from, to time.Time) (map[string]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[string]struct{}, len(postalCodes))
+	for _, postalCode := range postalCodes {
+		wanted[postalCode] = struct{}{}
+	}
+	fromDay := ""
+	if !from.IsZero() {
+		fromDay = from.UTC().Format("2006-01-02")
+	}
+
+	sketches := make(map[string]*util.HyperLogLog)
+	for key, sketch := range r.uniqueClients {
+		if _, ok := wanted[key.postalCode]; !ok {
+			continue
+		}
+		day, _ := time.Parse("2006-01-02", key.day)
+		if key.day < fromDay || (!to.IsZero() && !day.Before(to)) {
+			continue
+		}
+		if sketches[key.postalCode] == nil {
+			sketches[key.postalCode] = util.NewHyperLogLog()
+		}
+		sketches[key.postalCode].Merge(sketch)
+	}
+
+	counts := make(map[string]int, len(sketches))
+	for postalCode, sketch := range sketches {
+		counts[postalCode] = sketch.Count()
+	}
+	return counts, nil
+}
+
+// before より前のアクセスログを最大 batchSize 件、日次の集約に加えてから削除し、削除した件数を返す
+func (r *MemoryAccessLogRepository) RollupAndPurge(before time.Time, batchSize int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	kept := r.entries[:0]
+	for _, entry := range r.entries {
+		if n >= batchSize || !entry.CreatedAt.Before(before) {
+			kept = append(kept, entry)
+			continue
+		}
+		key := uniqueClientKey{day: entry.CreatedAt.UTC().Format("2006-01-02"), postalCode: entry.PostalCode}
+		if rollup, ok := r.rollups[key]; ok {
+			rollup.RequestCount++
+			if entry.CreatedAt.Before(rollup.FirstSeen) {
+				rollup.FirstSeen = entry.CreatedAt
+			}
+			if entry.CreatedAt.After(rollup.LastSeen) {
+				rollup.LastSeen = entry.CreatedAt
+			}
+		} else {
+			r.rollups[key] = &entity.AccessLog{PostalCode: entry.PostalCode, RequestCount: 1, FirstSeen: entry.CreatedAt, LastSeen: entry.CreatedAt}
+		}
+		n++
+	}
+	r.entries = kept
+	return n, nil
+}
+
+// メモリは常に利用できる
+func (r *MemoryAccessLogRepository) Ping() error {
+	return nil
+}
+
+// 郵便番号ごとの集計に件数と日時を加える
+func addToAccessLog(logs map[string]*entity.AccessLog, postalCode string, count int, firstSeen, lastSeen time.Time) {
+	log, ok := logs[postalCode]
+	if !ok {
+		logs[postalCode] = &entity.AccessLog{PostalCode: postalCode, RequestCount: count, FirstSeen: firstSeen, LastSeen: lastSeen}
+		return
+	}
+	log.RequestCount += count
+	if firstSeen.Before(log.FirstSeen) {
+		log.FirstSeen = firstSeen
+	}
+	if lastSeen.After(log.LastSeen) {
+		log.LastSeen = lastSeen
+	}
+}
+
+// 日時が期間内か（from・to がゼロ値の場合は絞り込まない）
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+func sortByPostalCode(logs []entity.AccessLog) {
+	sort.Slice(logs, func(i, j int) bool { return logs[i].PostalCode < logs[j].PostalCode })
+}
@@ -1,49 +1,359 @@
 package infra
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/dkpcb/finatext_kadai_2/entity"
 )
 
+// AccessLogRepository は SQL の DB に保存する entity.AccessLogRepository
 type AccessLogRepository struct {
-	DB *sql.DB
+	DB      *sql.DB
+	dialect dialect
 }
 
-// 新しい AccessLogRepository を作成
+// MySQL に保存する AccessLogRepository を作成
 func NewAccessLogRepository(db *sql.DB) *AccessLogRepository {
-	return &AccessLogRepository{DB: db}
+	return &AccessLogRepository{DB: db, dialect: mysqlDialect{}}
 }
 
-// // 郵便番号と作成日時を含むアクセスログを保存
-// func (r *AccessLogRepository) InsertAccessLog(postalCode string, createdAt time.Time) error {
-// 	query := "INSERT INTO access_logs (postal_code, created_at) VALUES (?, ?)"
-// 	_, err := r.DB.Exec(query, postalCode, createdAt)
-// 	return err
-// }
+// 方言に合わせてクエリを実行する
+func (r *AccessLogRepository) db() dialectQuerier {
+	return dialectQuerier{q: r.DB, d: r.dialect}
+}
 
-func (r *AccessLogRepository) InsertAccessLog(postalCode string, createdAt time.Time) error {
-	query := "INSERT INTO access_logs (postal_code, created_at) VALUES (?, ?)"
-	_, err := r.DB.Exec(query, postalCode, createdAt)
+// トランザクションを開始し、方言に合わせてクエリを実行できるようにする
+func (r *AccessLogRepository) begin() (*sql.Tx, dialectQuerier, error) {
+	tx, err := r.DB.Begin()
 	if err != nil {
-		fmt.Printf("Failed to execute query: %s with error: %v\n", query, err)
+		return nil, dialectQuerier{}, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	return err
+	return tx, dialectQuerier{q: tx, d: r.dialect}, nil
+}
+
+// アクセスログの文字列カラムの最大長
+const (
+	accessLogClientIPMaxLength   = 45
+	accessLogUserAgentMaxLength  = 512
+	accessLogRequestIDMaxLength  = 64
+	accessLogErrorClassMaxLength = 32
+)
+
+// 住所検索の結果を含むアクセスログを保存
+func (r *AccessLogRepository) InsertAccessLog(entry entity.AccessLogEntry) error {
+	return r.InsertAccessLogs([]entity.AccessLogEntry{entry})
 }
 
-// リクエスト回数を集計し、降順で返す
-func (r *AccessLogRepository) GetAccessLogs() ([]entity.AccessLog, error) {
-	// SQLクエリの更新：リクエスト回数を集計し降順で返す
+// 複数のアクセスログを複数行の INSERT でまとめて保存し、郵便番号ごとのカウンターとクライアントのスケッチに反映する
+// 同じ ID のアクセスログが保存済みの場合は保存も加算もしない（スプールからの再送を想定）
+func (r *AccessLogRepository) InsertAccessLogs(entries []entity.AccessLogEntry) (err error) {
+	if len(entries) == 0 {
+		return nil
+	}
+	defer func() {
+		if err != nil {
+			log.Printf("Failed to insert %d access logs: %v", len(entries), err)
+		}
+	}()
+
+	tx, q, err := r.begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if entries, err = excludeSavedEntries(q, entries); err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return tx.Commit()
+	}
+
+	values := make([]string, len(entries))
+	args := make([]interface{}, 0, len(entries)*13)
+	for i, entry := range entries {
+		values[i] = "(" + placeholders(13) + ")"
+		args = append(args,
+			nullIfEmpty(entry.ID),
+			truncate(entry.PostalCode, entity.AccessLogPostalCodeMaxLength),
+			entry.Status,
+			float64(entry.Latency.Microseconds())/1000,
+			truncate(entry.ClientIP, accessLogClientIPMaxLength),
+			truncate(entry.UserAgent, accessLogUserAgentMaxLength),
+			truncate(entry.RequestID, accessLogRequestIDMaxLength),
+			entry.HitCount,
+			truncate(entry.ErrorClass, accessLogErrorClassMaxLength),
+			entry.PrefectureCode,
+			entry.CityCode,
+			entry.ClientID,
+			entry.CreatedAt,
+		)
+	}
 	query := `
-		SELECT postal_code, COUNT(*) as request_count
-		FROM access_logs
-		GROUP BY postal_code
-		ORDER BY request_count DESC
-	`
+		INSERT INTO access_logs
+			(entry_id, postal_code, status, latency_ms, client_ip, user_agent, request_id, hit_count, error_class, prefecture_code, city_code, client_id, created_at)
+		VALUES ` + strings.Join(values, ", ")
+	if _, err = q.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to insert access logs: %w", err)
+	}
+
+	if err = incrementCounters(q, entries); err != nil {
+		return err
+	}
+	if err = mergeUniqueClients(q, entries); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit access logs: %w", err)
+	}
+	return nil
+}
+
+// 保存済みの ID のアクセスログを取り除く
+func excludeSavedEntries(q dialectQuerier, entries []entity.AccessLogEntry) ([]entity.AccessLogEntry, error) {
+	var ids []interface{}
+	for _, entry := range entries {
+		if entry.ID != "" {
+			ids = append(ids, entry.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	rows, err := q.Query("SELECT entry_id FROM access_logs WHERE entry_id IN ("+placeholders(len(ids))+")", ids...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch saved access log ids: %w", err)
+	}
+	defer rows.Close()
+
+	saved := make(map[string]struct{})
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan access log id: %w", err)
+		}
+		saved[id] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over saved access log ids: %w", err)
+	}
+	if len(saved) == 0 {
+		return entries, nil
+	}
+
+	fresh := make([]entity.AccessLogEntry, 0, len(entries)-len(saved))
+	for _, entry := range entries {
+		if _, ok := saved[entry.ID]; !ok {
+			fresh = append(fresh, entry)
+		}
+	}
+	return fresh, nil
+}
+
+// 郵便番号ごとのカウンターにアクセスログの件数と日時を反映する
+func incrementCounters(q dialectQuerier, entries []entity.AccessLogEntry) error {
+	counters := make(map[string]*entity.AccessLog)
+	for _, entry := range entries {
+		c, ok := counters[entry.PostalCode]
+		if !ok {
+			c = &entity.AccessLog{PostalCode: entry.PostalCode, FirstSeen: entry.CreatedAt, LastSeen: entry.CreatedAt}
+			counters[entry.PostalCode] = c
+		}
+		c.RequestCount++
+		if entry.CreatedAt.Before(c.FirstSeen) {
+			c.FirstSeen = entry.CreatedAt
+		}
+		if entry.CreatedAt.After(c.LastSeen) {
+			c.LastSeen = entry.CreatedAt
+		}
+	}
+
+	// 同時に更新するトランザクションとデッドロックしないよう、郵便番号の順にロックする
+	postalCodes := make([]string, 0, len(counters))
+	for postalCode := range counters {
+		postalCodes = append(postalCodes, postalCode)
+	}
+	sort.Strings(postalCodes)
+
+	values := make([]string, len(postalCodes))
+	args := make([]interface{}, 0, len(postalCodes)*4)
+	for i, postalCode := range postalCodes {
+		c := counters[postalCode]
+		values[i] = "(" + placeholders(4) + ")"
+		args = append(args, c.PostalCode, c.RequestCount, c.FirstSeen, c.LastSeen)
+	}
+	query := `
+		INSERT INTO access_log_counters (postal_code, request_count, first_seen, last_seen)
+		VALUES ` + strings.Join(values, ", ") + `
+		` + q.d.onConflict("postal_code") + `
+			request_count = access_log_counters.request_count + ` + q.d.excluded("request_count") + `,
+			first_seen = ` + q.d.least() + `(access_log_counters.first_seen, ` + q.d.excluded("first_seen") + `),
+			last_seen = ` + q.d.greatest() + `(access_log_counters.last_seen, ` + q.d.excluded("last_seen") + `)`
+	if _, err := q.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to increment access log counters: %w", err)
+	}
+	return nil
+}
+
+// 条件に一致するアクセスログを郵便番号ごとに集計して返す
+// 期間の指定がない場合はカウンターを、ある場合は生のアクセスログと日次の集約を合算して返す
+func (r *AccessLogRepository) GetAccessLogs(q entity.AccessLogQuery) ([]entity.AccessLog, error) {
+	var source string
+	var args []interface{}
+	if q.From.IsZero() && q.To.IsZero() {
+		source = "SELECT postal_code, request_count, first_seen, last_seen FROM access_log_counters"
+		if q.Prefix != "" {
+			source += " WHERE postal_code LIKE ?"
+			args = append(args, q.Prefix+"%")
+		}
+	} else {
+		source, args = aggregateAccessLogsQuery(q.From, q.To, q.Prefix)
+	}
+
+	query := "SELECT postal_code, request_count, first_seen, last_seen FROM (" + source + ") AS aggregated"
+
+	// 並び替えのキー（同じ値の場合は郵便番号で並べる）
+	key := "request_count"
+	switch q.Sort {
+	case entity.AccessLogSortPostalCode:
+		key = ""
+	case entity.AccessLogSortLastSeen:
+		key = "last_seen"
+	}
+	direction, cmp := "DESC", "<"
+	if q.Order == entity.OrderAsc {
+		direction, cmp = "ASC", ">"
+	}
+
+	// 前のページの最後の行より後ろの行だけを返す
+	if q.After != nil {
+		var value interface{} = q.After.RequestCount
+		if q.Sort == entity.AccessLogSortLastSeen {
+			value = q.After.LastSeen
+		}
+		if key == "" {
+			query += " WHERE postal_code " + cmp + " ?"
+			args = append(args, q.After.PostalCode)
+		} else {
+			query += fmt.Sprintf(" WHERE (%[1]s %[2]s ? OR (%[1]s = ? AND postal_code %[2]s ?))", key, cmp)
+			args = append(args, value, value, q.After.PostalCode)
+		}
+	}
+
+	if key == "" {
+		query += " ORDER BY postal_code " + direction
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, postal_code %s", key, direction, direction)
+	}
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	return r.queryAccessLogs(query, args...)
+}
+
+// 生のアクセスログと、保持期間を過ぎて日次に集約したアクセスログを郵便番号ごとに合算するクエリ
+// from・to がゼロ値の場合は期間で絞り込まない
+func aggregateAccessLogsQuery(from, to time.Time, prefix string) (string, []interface{}) {
+	var rawConditions, rollupConditions []string
+	var rawArgs, rollupArgs []interface{}
+	// 日次の集約は日の途中で分けられないため、期間に丸ごと含まれる日だけを含める
+	firstDay, endDay := wholeDays(from, to)
+	if !from.IsZero() {
+		rawConditions = append(rawConditions, "created_at >= ?")
+		rawArgs = append(rawArgs, from)
+		rollupConditions = append(rollupConditions, "day >= ?")
+		rollupArgs = append(rollupArgs, firstDay)
+	}
+	if !to.IsZero() {
+		rawConditions = append(rawConditions, "created_at < ?")
+		rawArgs = append(rawArgs, to)
+		rollupConditions = append(rollupConditions, "day < ?")
+		rollupArgs = append(rollupArgs, endDay)
+	}
+	if prefix != "" {
+		rawConditions = append(rawConditions, "postal_code LIKE ?")
+		rawArgs = append(rawArgs, prefix+"%")
+		rollupConditions = append(rollupConditions, "postal_code LIKE ?")
+		rollupArgs = append(rollupArgs, prefix+"%")
+	}
+
+	rawQuery := `
+		SELECT postal_code, COUNT(*) AS request_count, MIN(created_at) AS first_seen, MAX(created_at) AS last_seen
+		FROM access_logs`
+	if len(rawConditions) > 0 {
+		rawQuery += " WHERE " + strings.Join(rawConditions, " AND ")
+	}
+	rawQuery += " GROUP BY postal_code"
+
+	rollupQuery := `
+		SELECT postal_code, SUM(request_count) AS request_count, MIN(first_seen) AS first_seen, MAX(last_seen) AS last_seen
+		FROM access_log_daily_rollups`
+	if len(rollupConditions) > 0 {
+		rollupQuery += " WHERE " + strings.Join(rollupConditions, " AND ")
+	}
+	rollupQuery += " GROUP BY postal_code"
+
+	query := `
+		SELECT postal_code, SUM(request_count) AS request_count, MIN(first_seen) AS first_seen, MAX(last_seen) AS last_seen
+		FROM (` + rawQuery + `
+		UNION ALL` + rollupQuery + `
+		) AS combined
+		GROUP BY postal_code`
+	return query, append(rawArgs, rollupArgs...)
+}
+
+// 期間 [from, to) に丸ごと含まれる UTC の日の範囲 [firstDay, endDay)（YYYY-MM-DD）
+// from・to がゼロ値の場合は対応する値を空にする
+func wholeDays(from, to time.Time) (firstDay, endDay string) {
+	if !from.IsZero() {
+		day := from.UTC().Truncate(24 * time.Hour)
+		if day.Before(from) {
+			day = day.AddDate(0, 0, 1)
+		}
+		firstDay = day.Format("2006-01-02")
+	}
+	if !to.IsZero() {
+		endDay = to.UTC().Truncate(24 * time.Hour).Format("2006-01-02")
+	}
+	return firstDay, endDay
+}
+
+// 作成日時を期間で絞り込む WHERE 句（from・to がゼロ値の場合は絞り込まない）
+func createdAtCondition(from, to time.Time) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if !from.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, to)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// 郵便番号ごとの集計結果を取得
+func (r *AccessLogRepository) queryAccessLogs(query string, args ...interface{}) ([]entity.AccessLog, error) {
 	// クエリを実行
-	rows, err := r.DB.Query(query)
+	rows, err := r.db().Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch access logs: %w", err)
 	}
@@ -53,8 +363,7 @@ func (r *AccessLogRepository) GetAccessLogs() ([]entity.AccessLog, error) {
 	var logs []entity.AccessLog
 	for rows.Next() {
 		var log entity.AccessLog
-		// スキャン時に ID と CreatedAt を削除
-		if err := rows.Scan(&log.PostalCode, &log.RequestCount); err != nil {
+		if err := rows.Scan(&log.PostalCode, &log.RequestCount, sqlTime{&log.FirstSeen}, sqlTime{&log.LastSeen}); err != nil {
 			return nil, fmt.Errorf("failed to scan access log: %w", err)
 		}
 		logs = append(logs, log)
@@ -68,3 +377,250 @@ func (r *AccessLogRepository) GetAccessLogs() ([]entity.AccessLog, error) {
 	// 最終的なスライスを返す
 	return logs, nil
 }
+
+// 郵便番号ごとのカウンターをすべて返す
+func (r *AccessLogRepository) ListCounters() ([]entity.AccessLog, error) {
+	return r.queryAccessLogs("SELECT postal_code, request_count, first_seen, last_seen FROM access_log_counters ORDER BY postal_code")
+}
+
+// 生のアクセスログと日次の集約から郵便番号ごとの件数を計算して返す
+func (r *AccessLogRepository) ComputeCounters() ([]entity.AccessLog, error) {
+	query, args := aggregateAccessLogsQuery(time.Time{}, time.Time{}, "")
+	return r.queryAccessLogs(query+" ORDER BY postal_code", args...)
+}
+
+// カウンターを生のアクセスログと日次の集約から作り直す
+func (r *AccessLogRepository) RebuildCounters() (err error) {
+	tx, q, err := r.begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = q.Exec("DELETE FROM access_log_counters"); err != nil {
+		return fmt.Errorf("failed to clear access log counters: %w", err)
+	}
+	query, args := aggregateAccessLogsQuery(time.Time{}, time.Time{}, "")
+	if _, err = q.Exec("INSERT INTO access_log_counters (postal_code, request_count, first_seen, last_seen) "+query, args...); err != nil {
+		return fmt.Errorf("failed to rebuild access log counters: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit access log counters: %w", err)
+	}
+	return nil
+}
+
+// カウンターが空でアクセスログがある場合（カウンターの導入前のデータ）はカウンターを作り直す
+func (r *AccessLogRepository) InitializeCounters() error {
+	var hasCounters, hasLogs bool
+	if err := r.db().QueryRow("SELECT EXISTS (SELECT 1 FROM access_log_counters)").Scan(&hasCounters); err != nil {
+		return fmt.Errorf("failed to inspect access log counters: %w", err)
+	}
+	if hasCounters {
+		return nil
+	}
+	query := "SELECT EXISTS (SELECT 1 FROM access_logs) OR EXISTS (SELECT 1 FROM access_log_daily_rollups)"
+	if err := r.db().QueryRow(query).Scan(&hasLogs); err != nil {
+		return fmt.Errorf("failed to inspect access logs: %w", err)
+	}
+	if !hasLogs {
+		return nil
+	}
+	return r.RebuildCounters()
+}
+
+// 期間内の生のアクセスログを保存順に 1 件ずつ fn に渡す（全件をメモリに読み込まない）
+// from・to がゼロ値の場合は期間で絞り込まない。fn がエラーを返した場合はそこで中断する
+func (r *AccessLogRepository) StreamAccessLogs(ctx context.Context, from, to time.Time, fn func(entity.AccessLogEntry) error) error {
+	query := `
+		SELECT entry_id, postal_code, status, latency_ms, client_ip, user_agent, request_id, hit_count, error_class, prefecture_code, city_code, client_id, created_at
+		FROM access_logs`
+	where, args := createdAtCondition(from, to)
+	query += where
+	query += " ORDER BY id"
+
+	rows, err := r.db().QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch access logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry entity.AccessLogEntry
+		var id sql.NullString
+		var latencyMs float64
+		if err := rows.Scan(&id, &entry.PostalCode, &entry.Status, &latencyMs, &entry.ClientIP, &entry.UserAgent,
+			&entry.RequestID, &entry.HitCount, &entry.ErrorClass, &entry.PrefectureCode, &entry.CityCode, &entry.ClientID, sqlTime{&entry.CreatedAt}); err != nil {
+			return fmt.Errorf("failed to scan access log: %w", err)
+		}
+		entry.ID = id.String
+		entry.Latency = time.Duration(latencyMs * float64(time.Millisecond))
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate over access logs: %w", err)
+	}
+	return nil
+}
+
+// 期間内の生のアクセスログを地域コード（level に応じて都道府県または市区町村）ごとに集計して返す
+// 地域を解決できなかったアクセスはコードが空の行として返す
+func (r *AccessLogRepository) CountAccessLogsByRegion(level string, from, to time.Time) ([]entity.AccessLogRegionCount, error) {
+	column := "prefecture_code"
+	if level == entity.RegionLevelCity {
+		column = "city_code"
+	}
+
+	query := "SELECT " + column + ", COUNT(*) FROM access_logs"
+	where, args := createdAtCondition(from, to)
+	query += where
+	query += " GROUP BY " + column
+
+	rows, err := r.db().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count access logs by region: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []entity.AccessLogRegionCount
+	for rows.Next() {
+		var count entity.AccessLogRegionCount
+		if err := rows.Scan(&count.Code, &count.RequestCount); err != nil {
+			return nil, fmt.Errorf("failed to scan access log region count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over access log region counts: %w", err)
+	}
+	return counts, nil
+}
+
+// 期間内のアクセス数を 1 時間ごと（UTC）に集計して返す（postalCode が空の場合はすべての郵便番号）
+func (r *AccessLogRepository) CountAccessLogsByHour(postalCode string, from, to time.Time) ([]entity.AccessLogBucket, error) {
+	query := `
+		SELECT ` + r.dialect.hourOf("created_at") + ` AS hour, COUNT(*)
+		FROM access_logs
+		WHERE created_at >= ? AND created_at < ?
+	`
+	args := []interface{}{from, to}
+	if postalCode != "" {
+		query += " AND postal_code = ?"
+		args = append(args, postalCode)
+	}
+	query += " GROUP BY hour ORDER BY hour"
+
+	rows, err := r.db().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count access logs: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []entity.AccessLogBucket
+	for rows.Next() {
+		var hour string
+		var bucket entity.AccessLogBucket
+		if err := rows.Scan(&hour, &bucket.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan access log count: %w", err)
+		}
+		// created_at は UTC で保存している
+		if bucket.Start, err = time.ParseInLocation("2006-01-02 15:04:05", hour, time.UTC); err != nil {
+			return nil, fmt.Errorf("failed to parse access log hour: %w", err)
+		}
+		buckets = append(buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over access log counts: %w", err)
+	}
+	return buckets, nil
+}
+
+// before より前のアクセスログを最大 batchSize 件、日次の集約に加えてから削除し、削除した件数を返す
+// 集約と削除は同じトランザクションで行うため、途中で失敗しても二重に数えない
+func (r *AccessLogRepository) RollupAndPurge(before time.Time, batchSize int) (n int, err error) {
+	tx, q, err := r.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// 対象の行を古い順にロック
+	rows, err := q.Query("SELECT id FROM access_logs WHERE created_at < ? ORDER BY id LIMIT ?"+q.d.forUpdate(), before, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select expired access logs: %w", err)
+	}
+	var ids []interface{}
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan access log id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate over expired access logs: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, tx.Commit()
+	}
+	in := "(" + placeholders(len(ids)) + ")"
+
+	// 日次の集約に加算
+	rollupQuery := `
+		INSERT INTO access_log_daily_rollups (day, postal_code, request_count, first_seen, last_seen)
+		SELECT ` + q.d.dateOf("created_at") + `, postal_code, COUNT(*), MIN(created_at), MAX(created_at)
+		FROM access_logs
+		WHERE id IN ` + in + `
+		GROUP BY ` + q.d.dateOf("created_at") + `, postal_code
+		` + q.d.onConflict("day", "postal_code") + `
+			request_count = access_log_daily_rollups.request_count + ` + q.d.excluded("request_count") + `,
+			first_seen = ` + q.d.least() + `(access_log_daily_rollups.first_seen, ` + q.d.excluded("first_seen") + `),
+			last_seen = ` + q.d.greatest() + `(access_log_daily_rollups.last_seen, ` + q.d.excluded("last_seen") + `)
+	`
+	if _, err = q.Exec(rollupQuery, ids...); err != nil {
+		return 0, fmt.Errorf("failed to roll up access logs: %w", err)
+	}
+
+	if _, err = q.Exec("DELETE FROM access_logs WHERE id IN "+in, ids...); err != nil {
+		return 0, fmt.Errorf("failed to purge access logs: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit access log purge: %w", err)
+	}
+	return len(ids), nil
+}
+
+// DB に接続できるかを確認
+func (r *AccessLogRepository) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return r.DB.PingContext(ctx)
+}
+
+// 空文字列を NULL として扱う
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// 文字列を最大 n 文字に切り詰める
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
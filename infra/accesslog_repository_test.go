@@ -0,0 +1,295 @@
+package infra_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// すべての保存先で同じ振る舞いになることを確認する（MySQL・PostgreSQL は TEST_MYSQL_DSN・TEST_POSTGRES_DSN を指定した場合のみ）
+func TestAccessLogRepositoryConformance(t *testing.T) {
+	stores := map[string]func(t *testing.T) entity.AccessLogRepository{
+		"memory": func(t *testing.T) entity.AccessLogRepository {
+			return infra.NewMemoryAccessLogRepository()
+		},
+		"sqlite": func(t *testing.T) entity.AccessLogRepository {
+			repo, err := infra.NewSQLiteAccessLogRepository(filepath.Join(t.TempDir(), "access_logs.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { repo.DB.Close() })
+			return repo
+		},
+		"mysql":    newMySQLAccessLogRepository,
+		"postgres": newPostgresAccessLogRepository,
+	}
+
+	for name, newRepo := range stores {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			for _, tc := range conformanceTests {
+				tc := tc
+				t.Run(tc.name, func(t *testing.T) { tc.run(t, newRepo(t)) })
+			}
+		})
+	}
+}
+
+// 空のテーブルの MySQL に保存する AccessLogRepository
+func newMySQLAccessLogRepository(t *testing.T) entity.AccessLogRepository {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+
+	dbManager, err := infra.NewDBManager(infra.DriverMySQL, dsn, infra.DBOptions{})
+	require.NoError(t, err)
+	t.Cleanup(func() { dbManager.DB.Close() })
+	require.NoError(t, dbManager.InitializeSchema())
+	return emptyAccessLogRepository(t, dbManager)
+}
+
+// 空のテーブルの PostgreSQL に保存する AccessLogRepository
+func newPostgresAccessLogRepository(t *testing.T) entity.AccessLogRepository {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	dbManager, err := infra.NewDBManager(infra.DriverPostgres, dsn, infra.DBOptions{})
+	require.NoError(t, err)
+	t.Cleanup(func() { dbManager.DB.Close() })
+	require.NoError(t, dbManager.InitializeSchema())
+	return emptyAccessLogRepository(t, dbManager)
+}
+
+// アクセスログのテーブルを空にしてから AccessLogRepository を返す
+func emptyAccessLogRepository(t *testing.T, dbManager *infra.DBManager) entity.AccessLogRepository {
+	for _, table := range []string{"access_logs", "access_log_daily_rollups", "access_log_counters", "access_log_unique_clients"} {
+		_, err := dbManager.DB.Exec("DELETE FROM " + table)
+		require.NoError(t, err)
+	}
+	return dbManager.AccessLogRepository()
+}
+
+var base = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func entryAt(id, postalCode string, offset time.Duration) entity.AccessLogEntry {
+	return entity.AccessLogEntry{
+		ID:             id,
+		PostalCode:     postalCode,
+		Status:         200,
+		Latency:        1500 * time.Microsecond,
+		ClientIP:       "192.0.2.1",
+		UserAgent:      "test",
+		RequestID:      "req-" + id,
+		HitCount:       1,
+		PrefectureCode: postalCode[:2],
+		CityCode:       postalCode[:2] + "101",
+		ClientID:       "client-" + id,
+		CreatedAt:      base.Add(offset),
+	}
+}
+
+// 郵便番号と件数だけを取り出す
+func requestCounts(logs []entity.AccessLog) map[string]int {
+	counts := make(map[string]int, len(logs))
+	for _, log := range logs {
+		counts[log.PostalCode] = log.RequestCount
+	}
+	return counts
+}
+
+var conformanceTests = []struct {
+	name string
+	run  func(t *testing.T, repo entity.AccessLogRepository)
+}{
+	{"counters and first/last seen", func(t *testing.T, repo entity.AccessLogRepository) {
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{
+			entryAt("a1", "1000001", time.Hour),
+			entryAt("a2", "1000001", 0),
+			entryAt("a3", "2790031", 2*time.Hour),
+		}))
+		require.NoError(t, repo.InsertAccessLog(entryAt("a4", "1000001", 3*time.Hour)))
+
+		logs, err := repo.GetAccessLogs(entity.AccessLogQuery{})
+		require.NoError(t, err)
+		require.Len(t, logs, 2)
+		assert.Equal(t, "1000001", logs[0].PostalCode)
+		assert.Equal(t, 3, logs[0].RequestCount)
+		assert.True(t, base.Equal(logs[0].FirstSeen))
+		assert.True(t, base.Add(3*time.Hour).Equal(logs[0].LastSeen))
+		assert.Equal(t, "2790031", logs[1].PostalCode)
+	}},
+	{"duplicate ids are saved once", func(t *testing.T, repo entity.AccessLogRepository) {
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{entryAt("d1", "1000001", 0)}))
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{entryAt("d1", "1000001", 0), entryAt("d2", "1000001", 0)}))
+
+		logs, err := repo.GetAccessLogs(entity.AccessLogQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"1000001": 2}, requestCounts(logs))
+	}},
+	{"sort, prefix and keyset pagination", func(t *testing.T, repo entity.AccessLogRepository) {
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{
+			entryAt("p1", "1000001", 0),
+			entryAt("p2", "1000002", 0),
+			entryAt("p3", "1000002", time.Minute),
+			entryAt("p4", "1000003", 0),
+			entryAt("p5", "2790031", 0),
+		}))
+
+		q := entity.AccessLogQuery{Prefix: "100", Sort: entity.AccessLogSortCount, Order: entity.OrderDesc, Limit: 2}
+		page, err := repo.GetAccessLogs(q)
+		require.NoError(t, err)
+		require.Len(t, page, 2)
+		assert.Equal(t, "1000002", page[0].PostalCode)
+		assert.Equal(t, "1000003", page[1].PostalCode)
+
+		last := page[1]
+		q.After = &entity.AccessLogCursor{Sort: q.Sort, Order: q.Order, RequestCount: last.RequestCount, LastSeen: last.LastSeen, PostalCode: last.PostalCode}
+		page, err = repo.GetAccessLogs(q)
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, "1000001", page[0].PostalCode)
+
+		page, err = repo.GetAccessLogs(entity.AccessLogQuery{Sort: entity.AccessLogSortPostalCode, Order: entity.OrderAsc})
+		require.NoError(t, err)
+		require.Len(t, page, 4)
+		assert.Equal(t, "1000001", page[0].PostalCode)
+		assert.Equal(t, "2790031", page[3].PostalCode)
+	}},
+	{"time range", func(t *testing.T, repo entity.AccessLogRepository) {
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{
+			entryAt("r1", "1000001", 0),
+			entryAt("r2", "1000001", time.Hour),
+			entryAt("r3", "2790031", 2*time.Hour),
+		}))
+
+		logs, err := repo.GetAccessLogs(entity.AccessLogQuery{From: base.Add(time.Hour), To: base.Add(2 * time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"1000001": 1}, requestCounts(logs))
+	}},
+	{"hourly and regional counts", func(t *testing.T, repo entity.AccessLogRepository) {
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{
+			entryAt("h1", "1000001", 0),
+			entryAt("h2", "1000001", 30*time.Minute),
+			entryAt("h3", "2790031", 90*time.Minute),
+		}))
+
+		buckets, err := repo.CountAccessLogsByHour("", base, base.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, buckets, 2)
+		assert.True(t, base.Equal(buckets[0].Start))
+		assert.Equal(t, 2, buckets[0].Count)
+		assert.True(t, base.Add(time.Hour).Equal(buckets[1].Start))
+		assert.Equal(t, 1, buckets[1].Count)
+
+		buckets, err = repo.CountAccessLogsByHour("2790031", base, base.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, buckets, 1)
+
+		counts, err := repo.CountAccessLogsByRegion(entity.RegionLevelPrefecture, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []entity.AccessLogRegionCount{{Code: "10", RequestCount: 2}, {Code: "27", RequestCount: 1}}, counts)
+	}},
+	{"stream in insertion order", func(t *testing.T, repo entity.AccessLogRepository) {
+		first, second := entryAt("s1", "1000001", time.Hour), entryAt("s2", "2790031", 0)
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{first}))
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{second}))
+
+		var streamed []entity.AccessLogEntry
+		require.NoError(t, repo.StreamAccessLogs(context.Background(), time.Time{}, time.Time{}, func(entry entity.AccessLogEntry) error {
+			streamed = append(streamed, entry)
+			return nil
+		}))
+		require.Len(t, streamed, 2)
+		assert.Equal(t, first.ID, streamed[0].ID)
+		assert.Equal(t, first.Latency, streamed[0].Latency)
+		assert.Equal(t, first.CityCode, streamed[0].CityCode)
+		assert.Equal(t, first.ClientID, streamed[0].ClientID)
+		assert.True(t, first.CreatedAt.Equal(streamed[0].CreatedAt))
+		assert.Equal(t, second.ID, streamed[1].ID)
+	}},
+	{"rollup keeps totals and counters consistent", func(t *testing.T, repo entity.AccessLogRepository) {
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{
+			entryAt("u1", "1000001", 0),
+			entryAt("u2", "1000001", time.Hour),
+			entryAt("u3", "1000001", 48*time.Hour),
+		}))
+
+		n, err := repo.RollupAndPurge(base.Add(24*time.Hour), 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		n, err = repo.RollupAndPurge(base.Add(24*time.Hour), 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		day := base.Truncate(24 * time.Hour)
+		logs, err := repo.GetAccessLogs(entity.AccessLogQuery{From: day, To: day.Add(72 * time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"1000001": 3}, requestCounts(logs))
+
+		// 日の途中から始まる期間では、その日の集約を数えない（集約した 2 件を丸ごと数えて多くならないようにする）
+		logs, err = repo.GetAccessLogs(entity.AccessLogQuery{From: base.Add(30 * time.Minute), To: day.Add(72 * time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"1000001": 1}, requestCounts(logs))
+
+		stored, err := repo.ListCounters()
+		require.NoError(t, err)
+		actual, err := repo.ComputeCounters()
+		require.NoError(t, err)
+		assert.Equal(t, requestCounts(stored), requestCounts(actual))
+
+		require.NoError(t, repo.RebuildCounters())
+		rebuilt, err := repo.ListCounters()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"1000001": 3}, requestCounts(rebuilt))
+		assert.True(t, base.Equal(rebuilt[0].FirstSeen))
+	}},
+	{"unique clients", func(t *testing.T, repo entity.AccessLogRepository) {
+		shared := entryAt("c1", "1000001", 0)
+		again := entryAt("c2", "1000001", time.Hour)
+		again.ClientID = shared.ClientID
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{shared, again, entryAt("c3", "1000001", 0)}))
+		require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{entryAt("c4", "1000001", 48*time.Hour)}))
+
+		counts, err := repo.CountUniqueClients([]string{"1000001", "2790031"}, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"1000001": 3}, counts)
+
+		counts, err = repo.CountUniqueClients([]string{"1000001"}, base, base.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"1000001": 2}, counts)
+	}},
+	{"concurrent first sketches are merged", func(t *testing.T, repo entity.AccessLogRepository) {
+		// 同じ日・郵便番号の最初のスケッチを同時に作成しても、互いのクライアントを上書きしない
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- repo.InsertAccessLogs([]entity.AccessLogEntry{entryAt(fmt.Sprintf("s%d", i), "1000001", 0)})
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		counts, err := repo.CountUniqueClients([]string{"1000001"}, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"1000001": 8}, counts)
+	}},
+	{"ping", func(t *testing.T, repo entity.AccessLogRepository) {
+		assert.NoError(t, repo.Ping())
+	}},
+}
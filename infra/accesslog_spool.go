@@ -0,0 +1,115 @@
+package infra
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+)
+
+// スプールから 1 回に再送する件数
+const spoolReplayBatchSize = 500
+
+// FileAccessLogSpool は DB に保存できなかったアクセスログを 1 行 1 件の JSON で追記するファイル
+type FileAccessLogSpool struct {
+	Path string
+	mu   sync.Mutex // 追記と再送用ファイルへの切り替えを排他する
+}
+
+// 新しい FileAccessLogSpool を作成
+func NewFileAccessLogSpool(path string) *FileAccessLogSpool {
+	return &FileAccessLogSpool{Path: path}
+}
+
+// アクセスログをファイルに追記し、ディスクに書き込む
+func (s *FileAccessLogSpool) Append(entries []entity.AccessLogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open access log spool: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to encode spooled access log: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write access log spool: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync access log spool: %w", err)
+	}
+	return nil
+}
+
+// スプールしたアクセスログを insert でまとめて再送し、再送した件数を返す
+// 再送中のファイルは別名に切り替え、すべて再送できた場合のみ削除する
+// 途中で失敗した場合は次回に先頭から再送するため、insert は同じ ID を重複して保存しないこと
+func (s *FileAccessLogSpool) Replay(insert func([]entity.AccessLogEntry) error) (int, error) {
+	replayPath := s.Path + ".replay"
+
+	// 前回の再送が途中で終わっていなければ、現在のファイルを再送用に切り替える
+	s.mu.Lock()
+	if _, err := os.Stat(replayPath); errors.Is(err, os.ErrNotExist) {
+		if err := os.Rename(s.Path, replayPath); err != nil {
+			s.mu.Unlock()
+			if errors.Is(err, os.ErrNotExist) {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("failed to rotate access log spool: %w", err)
+		}
+	}
+	s.mu.Unlock()
+
+	f, err := os.Open(replayPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open access log spool: %w", err)
+	}
+	defer f.Close()
+
+	replayed := 0
+	batch := make([]entity.AccessLogEntry, 0, spoolReplayBatchSize)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry entity.AccessLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// 書き込み途中で停止した行は読み飛ばす
+			log.Printf("Skipping malformed access log spool line %d: %v", line, err)
+			continue
+		}
+		batch = append(batch, entry)
+		if len(batch) >= spoolReplayBatchSize {
+			if err := insert(batch); err != nil {
+				return replayed, err
+			}
+			replayed += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return replayed, fmt.Errorf("failed to read access log spool: %w", err)
+	}
+	if len(batch) > 0 {
+		if err := insert(batch); err != nil {
+			return replayed, err
+		}
+		replayed += len(batch)
+	}
+
+	if err := os.Remove(replayPath); err != nil {
+		return replayed, fmt.Errorf("failed to remove replayed access log spool: %w", err)
+	}
+	return replayed, nil
+}
@@ -0,0 +1,173 @@
+package infra
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/util"
+)
+
+// 郵便番号ごと・日ごとのスケッチのキー
+type uniqueClientKey struct {
+	day        string // YYYY-MM-DD（UTC）
+	postalCode string
+}
+
+// アクセスログのクライアントを郵便番号ごと・日ごとの HyperLogLog に合わせる
+func mergeUniqueClients(q dialectQuerier, entries []entity.AccessLogEntry) error {
+	sketches := make(map[uniqueClientKey]*util.HyperLogLog)
+	for _, entry := range entries {
+		if entry.ClientID == "" {
+			continue
+		}
+		key := uniqueClientKey{day: entry.CreatedAt.UTC().Format("2006-01-02"), postalCode: entry.PostalCode}
+		if sketches[key] == nil {
+			sketches[key] = util.NewHyperLogLog()
+		}
+		sketches[key].Add(entry.ClientID)
+	}
+	if len(sketches) == 0 {
+		return nil
+	}
+
+	// 同時に更新するトランザクションとデッドロックしないよう、日付と郵便番号の順にロックする
+	keys := make([]uniqueClientKey, 0, len(sketches))
+	for key := range sketches {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].day != keys[j].day {
+			return keys[i].day < keys[j].day
+		}
+		return keys[i].postalCode < keys[j].postalCode
+	})
+
+	// まだない行は SELECT ... FOR UPDATE でロックできないため、先に空のスケッチを挿入して行を作る
+	// 同じ行を同時に挿入するトランザクションは一意制約で待たされ、読み込みと更新が直列になる
+	// （SQLite はトランザクションの開始時に DB 全体の書き込みロックを取るため、もともと直列になる）
+	empty := util.NewHyperLogLog().Bytes()
+	values := make([]string, len(keys))
+	args := make([]interface{}, 0, len(keys)*3)
+	for i, key := range keys {
+		values[i] = "(" + placeholders(3) + ")"
+		args = append(args, key.day, key.postalCode, empty)
+	}
+	query := `
+		INSERT INTO access_log_unique_clients (day, postal_code, sketch)
+		VALUES ` + strings.Join(values, ", ") + `
+		` + q.d.onConflictDoNothing("day", "postal_code")
+	if _, err := q.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to create unique client sketches: %w", err)
+	}
+
+	// 保存済みのスケッチをロックして読み込み、合わせる
+	conditions := make([]string, len(keys))
+	args = make([]interface{}, 0, len(keys)*2)
+	for i, key := range keys {
+		conditions[i] = "(day = ? AND postal_code = ?)"
+		args = append(args, key.day, key.postalCode)
+	}
+	query = "SELECT day, postal_code, sketch FROM access_log_unique_clients WHERE " + strings.Join(conditions, " OR ") + q.d.forUpdate()
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch unique client sketches: %w", err)
+	}
+	for rows.Next() {
+		var day time.Time
+		var key uniqueClientKey
+		var b []byte
+		if err := rows.Scan(sqlTime{&day}, &key.postalCode, &b); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan unique client sketch: %w", err)
+		}
+		key.day = day.Format("2006-01-02")
+		stored, err := util.HyperLogLogFromBytes(b)
+		if err != nil {
+			// 壊れたスケッチは新しいスケッチで置き換える
+			continue
+		}
+		if sketch, ok := sketches[key]; ok {
+			sketch.Merge(stored)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("failed to iterate over unique client sketches: %w", err)
+	}
+	rows.Close()
+
+	args = make([]interface{}, 0, len(keys)*3)
+	for _, key := range keys {
+		args = append(args, key.day, key.postalCode, sketches[key].Bytes())
+	}
+	query = `
+		INSERT INTO access_log_unique_clients (day, postal_code, sketch)
+		VALUES ` + strings.Join(values, ", ") + `
+		` + q.d.onConflict("day", "postal_code") + ` sketch = ` + q.d.excluded("sketch")
+	if _, err := q.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to save unique client sketches: %w", err)
+	}
+	return nil
+}
+
+// 郵便番号ごとに、期間と重なる日（UTC）のスケッチを合わせてクライアントの種類数を推定する
+// from・to がゼロ値の場合は期間で絞り込まない
+func (r *AccessLogRepository) CountUniqueClients(postalCodes []string, from, to time.Time) (map[string]int, error) {
+	counts := make(map[string]int, len(postalCodes))
+	if len(postalCodes) == 0 {
+		return counts, nil
+	}
+
+	query := "SELECT postal_code, sketch FROM access_log_unique_clients WHERE postal_code IN (" + placeholders(len(postalCodes)) + ")"
+	args := make([]interface{}, 0, len(postalCodes)+2)
+	for _, postalCode := range postalCodes {
+		args = append(args, postalCode)
+	}
+	if !from.IsZero() {
+		query += " AND day >= ?"
+		args = append(args, from.UTC().Format("2006-01-02"))
+	}
+	if !to.IsZero() {
+		// to を含む日は to が 0 時ちょうどでなければ含める
+		end := time.Date(to.UTC().Year(), to.UTC().Month(), to.UTC().Day(), 0, 0, 0, 0, time.UTC)
+		if end.Before(to) {
+			end = end.AddDate(0, 0, 1)
+		}
+		query += " AND day < ?"
+		args = append(args, end.Format("2006-01-02"))
+	}
+
+	rows, err := r.db().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch unique client sketches: %w", err)
+	}
+	defer rows.Close()
+
+	sketches := make(map[string]*util.HyperLogLog)
+	for rows.Next() {
+		var postalCode string
+		var b []byte
+		if err := rows.Scan(&postalCode, &b); err != nil {
+			return nil, fmt.Errorf("failed to scan unique client sketch: %w", err)
+		}
+		stored, err := util.HyperLogLogFromBytes(b)
+		if err != nil {
+			continue
+		}
+		if sketches[postalCode] == nil {
+			sketches[postalCode] = util.NewHyperLogLog()
+		}
+		sketches[postalCode].Merge(stored)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over unique client sketches: %w", err)
+	}
+
+	for postalCode, sketch := range sketches {
+		counts[postalCode] = sketch.Count()
+	}
+	return counts, nil
+}
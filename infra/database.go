@@ -1,79 +1,313 @@
 package infra
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/dkpcb/finatext_kadai_2/util"
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq" // database/sql の "postgres" ドライバー
 )
 
 // DBManager はデータベース操作の責務を持つ構造体
 type DBManager struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Driver string // DriverMySQL、DriverPostgres または DriverSQLite
+
+	mu      sync.RWMutex
+	pingErr error // 直近の疎通確認の結果
 }
 
-// DBManager を初期化
-func NewDBManager(dsn string) (*DBManager, error) {
-	db, err := initializeConnection(dsn)
+// DBOptions は接続プールと起動時の接続の再試行の設定（0 の項目はドライバーの既定値のまま）
+type DBOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	ConnectAttempts int           // 1 未満の場合は 1 回だけ試す
+	InitialBackoff  time.Duration // 最初の再試行までの待ち時間（以降は倍々に増やす）
+	MaxBackoff      time.Duration // 再試行までの待ち時間の上限（0 の場合は上限なし）
+}
+
+// DBManager を初期化（driver は DriverMySQL または DriverPostgres）
+func NewDBManager(driver, dsn string, opts DBOptions) (*DBManager, error) {
+	db, err := initializeConnection(driver, dsn, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return &DBManager{DB: db}, nil
+	return &DBManager{DB: db, Driver: driver}, nil
 }
 
-// iデータベース接続を初期化
-func initializeConnection(dsn string) (*sql.DB, error) {
-	var db *sql.DB
-	var err error
+// DB の種類に合わせてアクセスログを保存する AccessLogRepository を作成
+func (m *DBManager) AccessLogRepository() *AccessLogRepository {
+	return &AccessLogRepository{DB: m.DB, dialect: dialectOf(m.Driver)}
+}
+
+// DB の種類に合わせて郵便番号データセットを保存する PostalDatasetRepository を作成
+func (m *DBManager) PostalDatasetRepository() *PostalDatasetRepository {
+	return &PostalDatasetRepository{DB: m.DB, dialect: dialectOf(m.Driver)}
+}
+
+// データベース接続を初期化（接続できるまで待ち時間を倍々に増やしながら再試行する）
+func initializeConnection(driver, dsn string, opts DBOptions) (*sql.DB, error) {
+	switch driver {
+	case DriverMySQL:
+		// DATETIME 型を time.Time として読み込めるようにする
+		mysqlCfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DSN: %w", err)
+		}
+		if mysqlCfg.DBName == "" {
+			return nil, fmt.Errorf("DSN must include a database name")
+		}
+		mysqlCfg.ParseTime = true
+		dsn = mysqlCfg.FormatDSN()
+	case DriverPostgres:
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", driver)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
 
-	for i := 0; i < 2; i++ {
-		db, err = sql.Open("mysql", dsn)
-		if err == nil {
-			err = db.Ping()
-			if err == nil {
-				fmt.Println("Database connection successful!")
-				return db, nil
-			}
+	attempts := opts.ConnectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for i := 0; i < attempts; i++ {
+		if err = pingDatabase(db, driver, dsn); err == nil {
+			fmt.Println("Database connection successful!")
+			return db, nil
 		}
-		// リトライ中はログを一度だけ表示
-		if i == 0 {
-			fmt.Println("Retrying database connection...")
+		if i < attempts-1 {
+			delay := util.ExponentialBackoff(i, opts.InitialBackoff, opts.MaxBackoff)
+			log.Printf("Database connection failed (attempt %d/%d), retrying in %s: %v", i+1, attempts, delay, err)
+			time.Sleep(delay)
 		}
-		time.Sleep(5 * time.Second)
 	}
 
 	// すべてのリトライが失敗した場合のみエラーを返す
-	return nil, fmt.Errorf("failed to connect to database after 5 attempts: %w", err)
+	db.Close()
+	return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", attempts, err)
 }
 
-// データベースとテーブルを作成
-func (m *DBManager) InitializeSchema(databaseName string) error {
-	// データベース作成
-	createDBQuery := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s;", databaseName)
-	if _, err := m.DB.Exec(createDBQuery); err != nil {
-		return fmt.Errorf("failed to create database: %w", err)
+// DB に接続できるか確認する（MySQL で DSN のデータベースがまだない場合は作成する）
+func pingDatabase(db *sql.DB, driver, dsn string) error {
+	err := db.Ping()
+	var mysqlErr *mysql.MySQLError
+	if driver != DriverMySQL || !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlErrUnknownDatabase {
+		return err
 	}
+	if err := createMySQLDatabase(dsn); err != nil {
+		return err
+	}
+	return db.Ping()
+}
+
+// MySQL の Unknown database のエラー番号
+const mysqlErrUnknownDatabase = 1049
+
+// DSN のデータベースを作成
+func createMySQLDatabase(dsn string) error {
+	mysqlCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return fmt.Errorf("invalid DSN: %w", err)
+	}
+	databaseName := mysqlCfg.DBName
+	mysqlCfg.DBName = ""
+
+	db, err := sql.Open(DriverMySQL, mysqlCfg.FormatDSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	createDBQuery := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", strings.ReplaceAll(databaseName, "`", "``"))
+	if _, err := db.Exec(createDBQuery); err != nil {
+		return fmt.Errorf("failed to create database %s: %w", databaseName, err)
+	}
+	fmt.Printf("Database %s created.\n", databaseName)
+	return nil
+}
+
+// ctx が終了するまで interval ごとに DB の疎通を確認し、結果を Ready に反映する
+func (m *DBManager) RunHealthCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		err := m.DB.PingContext(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
 
-	// 使用するデータベースを指定
-	useDBQuery := fmt.Sprintf("USE %s;", databaseName)
-	if _, err := m.DB.Exec(useDBQuery); err != nil {
-		return fmt.Errorf("failed to use database: %w", err)
+		m.mu.Lock()
+		prev := m.pingErr
+		m.pingErr = err
+		m.mu.Unlock()
+
+		// 状態が変わったときだけログに出す
+		switch {
+		case err != nil && prev == nil:
+			log.Printf("Database health check failed: %v", err)
+		case err == nil && prev != nil:
+			log.Printf("Database health check recovered")
+		}
 	}
+}
+
+// 直近の疎通確認で DB に接続できなかった場合はそのエラーを返す
+func (m *DBManager) Ready() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pingErr
+}
 
-	// テーブル作成
-	createTableQuery := `
-	CREATE TABLE IF NOT EXISTS access_logs (
-		id INT AUTO_INCREMENT NOT NULL,
-		postal_code VARCHAR(8) NOT NULL,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		PRIMARY KEY (id)
-	);`
-	if _, err := m.DB.Exec(createTableQuery); err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
+// 未適用のマイグレーションを DSN のデータベースに適用する
+// DB にこのバイナリの知らないバージョンが適用済みの場合は ErrUnknownSchemaVersion を返す
+func (m *DBManager) InitializeSchema() error {
+	if _, err := m.MigrateUp(context.Background()); err != nil {
+		return err
 	}
 
 	fmt.Println("Database and table initialized successfully.")
 	return nil
 }
+
+// マイグレーションの導入前に作成した access_logs であることを示すテーブルのコメント
+// 最初のマイグレーションを戻しても、このコメントのある access_logs は削除しない
+const legacyAccessLogsComment = "created before schema migrations"
+
+// マイグレーションの導入前に作成した access_logs に、後から追加したカラムとインデックスを追加する（MySQL のみ）
+// 最初のマイグレーションは既存のテーブルをそのまま使うため、適用する前に列を揃える
+// 最初のマイグレーションを列を揃えずに記録した DB も直せるよう、適用済みかどうかによらず確認する
+func (m *DBManager) upgradeLegacySchema(ctx context.Context, conn *sql.Conn, initialApplied bool) error {
+	var exists int
+	query := "SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'access_logs'"
+	if err := conn.QueryRowContext(ctx, query).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to inspect legacy schema: %w", err)
+	}
+	if exists == 0 {
+		return nil
+	}
+
+	// 最初のマイグレーションより前からあるか、列が足りない access_logs は導入前のもの
+	legacy := !initialApplied
+	for _, column := range accessLogColumns {
+		added, err := addColumnIfMissing(ctx, conn, "access_logs", column.name, column.definition)
+		if err != nil {
+			return err
+		}
+		legacy = legacy || added
+	}
+	if err := addIndexIfMissing(ctx, conn, "access_logs", "idx_access_logs_entry_id", "UNIQUE INDEX idx_access_logs_entry_id (entry_id)"); err != nil {
+		return err
+	}
+	if err := addIndexIfMissing(ctx, conn, "access_logs", "idx_access_logs_created_at", "INDEX idx_access_logs_created_at (created_at)"); err != nil {
+		return err
+	}
+	if legacy {
+		if _, err := conn.ExecContext(ctx, "ALTER TABLE access_logs COMMENT = '"+legacyAccessLogsComment+"'"); err != nil {
+			return fmt.Errorf("failed to mark legacy access_logs: %w", err)
+		}
+	}
+	return nil
+}
+
+// access_logs がマイグレーションの導入前に作成したものか（MySQL のみ）
+func isLegacyAccessLogs(ctx context.Context, conn *sql.Conn) (bool, error) {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'access_logs' AND TABLE_COMMENT = ?
+	`
+	if err := conn.QueryRowContext(ctx, query, legacyAccessLogsComment).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to inspect legacy schema: %w", err)
+	}
+	return count > 0, nil
+}
+
+// access_logs に後から追加したカラム
+var accessLogColumns = []struct {
+	name, definition string
+}{
+	{"status", "SMALLINT NOT NULL DEFAULT 0"},
+	{"latency_ms", "DOUBLE NOT NULL DEFAULT 0"},
+	{"client_ip", "VARCHAR(45) NOT NULL DEFAULT ''"},
+	{"user_agent", "VARCHAR(512) NOT NULL DEFAULT ''"},
+	{"request_id", "VARCHAR(64) NOT NULL DEFAULT ''"},
+	{"hit_count", "INT NOT NULL DEFAULT 0"},
+	{"error_class", "VARCHAR(32) NOT NULL DEFAULT ''"},
+	{"entry_id", "CHAR(32) NULL"},
+	{"prefecture_code", "VARCHAR(2) NOT NULL DEFAULT ''"},
+	{"city_code", "VARCHAR(5) NOT NULL DEFAULT ''"},
+	{"client_id", "CHAR(32) NOT NULL DEFAULT ''"},
+}
+
+// テーブルにカラムがなければ追加し、追加したかを返す
+func addColumnIfMissing(ctx context.Context, conn *sql.Conn, table, column, definition string) (bool, error) {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?
+	`
+	if err := conn.QueryRowContext(ctx, query, table, column).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to inspect column %s.%s: %w", table, column, err)
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := conn.ExecContext(ctx, alterQuery); err != nil {
+		return false, fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return true, nil
+}
+
+// テーブルにインデックスがなければ追加
+func addIndexIfMissing(ctx context.Context, conn *sql.Conn, table, index, definition string) error {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?
+	`
+	if err := conn.QueryRowContext(ctx, query, table, index).Scan(&count); err != nil {
+		return fmt.Errorf("failed to inspect index %s.%s: %w", table, index, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	alterQuery := fmt.Sprintf("ALTER TABLE %s ADD %s", table, definition)
+	if _, err := conn.ExecContext(ctx, alterQuery); err != nil {
+		return fmt.Errorf("failed to add index %s.%s: %w", table, index, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package infra_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDBManagerRetries(t *testing.T) {
+	// 接続できない DB には指定した回数だけ試してから諦める
+	_, err := infra.NewDBManager(infra.DriverPostgres, "postgres://user@127.0.0.1:1/db?sslmode=disable&connect_timeout=1", infra.DBOptions{
+		ConnectAttempts: 3,
+		InitialBackoff:  time.Millisecond,
+		MaxBackoff:      2 * time.Millisecond,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "after 3 attempts")
+}
+
+func TestNewDBManagerRequiresDatabaseName(t *testing.T) {
+	_, err := infra.NewDBManager(infra.DriverMySQL, "user:password@tcp(127.0.0.1:1)/", infra.DBOptions{})
+	assert.Error(t, err)
+}
+
+func TestDBManagerHealthCheck(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "health.db"))
+	require.NoError(t, err)
+	dbManager := &infra.DBManager{DB: db}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dbManager.RunHealthCheck(ctx, 10*time.Millisecond)
+
+	assert.NoError(t, dbManager.Ready())
+
+	// 接続できなくなったら Ready がエラーを返す
+	require.NoError(t, db.Close())
+	assert.Eventually(t, func() bool { return dbManager.Ready() != nil }, time.Second, 10*time.Millisecond)
+}
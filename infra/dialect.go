@@ -0,0 +1,237 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dialect は DB ごとに異なる SQL の書き方
+type dialect interface {
+	// 値の変換（time.Time など、ドライバーがそのまま扱えない値を変換する）
+	bindValue(v interface{}) interface{}
+	// ? のプレースホルダーを DB の書き方にする
+	rebind(query string) string
+	// 一意制約に違反した場合に更新する句の先頭（続けて "列 = 式" を書く）
+	onConflict(columns ...string) string
+	// onConflict の更新式で、挿入しようとした値を参照する式
+	excluded(column string) string
+	// 一意制約に違反した場合に何もしない句
+	onConflictDoNothing(columns ...string) string
+	// 引数のうち小さい方・大きい方を返す関数名
+	least() string
+	greatest() string
+	// 選択した行をロックする句（ロックできない DB では空）
+	forUpdate() string
+	// 日時の列を UTC の 'YYYY-MM-DD HH:00:00' の文字列にする式
+	hourOf(column string) string
+	// 日時の列を日付にする式
+	dateOf(column string) string
+}
+
+// mysqlDialect は MySQL の方言
+type mysqlDialect struct{}
+
+func (mysqlDialect) bindValue(v interface{}) interface{} { return v }
+
+func (mysqlDialect) rebind(query string) string { return query }
+
+func (mysqlDialect) onConflict(columns ...string) string { return "ON DUPLICATE KEY UPDATE" }
+
+func (mysqlDialect) excluded(column string) string { return "VALUES(" + column + ")" }
+
+// INSERT IGNORE は一意制約以外のエラーも無視するため、同じ値で更新する
+func (mysqlDialect) onConflictDoNothing(columns ...string) string {
+	return "ON DUPLICATE KEY UPDATE " + columns[0] + " = " + columns[0]
+}
+
+func (mysqlDialect) least() string { return "LEAST" }
+
+func (mysqlDialect) greatest() string { return "GREATEST" }
+
+func (mysqlDialect) forUpdate() string { return " FOR UPDATE" }
+
+func (mysqlDialect) hourOf(column string) string {
+	return "DATE_FORMAT(" + column + ", '%Y-%m-%d %H:00:00')"
+}
+
+func (mysqlDialect) dateOf(column string) string { return "DATE(" + column + ")" }
+
+// sqliteDialect は SQLite の方言
+// 日時は UTC の固定長の文字列で保存し、文字列の比較で大小を判定できるようにする
+type sqliteDialect struct{}
+
+// SQLite に保存する日時の書式
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000"
+
+func (sqliteDialect) bindValue(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return t.UTC().Format(sqliteTimeFormat)
+	}
+	return v
+}
+
+func (sqliteDialect) rebind(query string) string { return query }
+
+func (sqliteDialect) onConflict(columns ...string) string {
+	return "ON CONFLICT (" + strings.Join(columns, ", ") + ") DO UPDATE SET"
+}
+
+func (sqliteDialect) excluded(column string) string { return "excluded." + column }
+
+func (sqliteDialect) onConflictDoNothing(columns ...string) string {
+	return "ON CONFLICT (" + strings.Join(columns, ", ") + ") DO NOTHING"
+}
+
+func (sqliteDialect) least() string { return "MIN" }
+
+func (sqliteDialect) greatest() string { return "MAX" }
+
+func (sqliteDialect) forUpdate() string { return "" }
+
+func (sqliteDialect) hourOf(column string) string {
+	return "strftime('%Y-%m-%d %H:00:00', " + column + ")"
+}
+
+func (sqliteDialect) dateOf(column string) string { return "date(" + column + ")" }
+
+// postgresDialect は PostgreSQL の方言
+type postgresDialect struct{}
+
+// 日時は TIMESTAMP（タイムゾーンなし）に UTC で保存する
+func (postgresDialect) bindValue(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return t.UTC()
+	}
+	return v
+}
+
+// ? を $1, $2, ... に置き換える（クエリの文字列リテラルには ? を書かない）
+func (postgresDialect) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (postgresDialect) onConflict(columns ...string) string {
+	return "ON CONFLICT (" + strings.Join(columns, ", ") + ") DO UPDATE SET"
+}
+
+func (postgresDialect) excluded(column string) string { return "EXCLUDED." + column }
+
+func (postgresDialect) onConflictDoNothing(columns ...string) string {
+	return "ON CONFLICT (" + strings.Join(columns, ", ") + ") DO NOTHING"
+}
+
+func (postgresDialect) least() string { return "LEAST" }
+
+func (postgresDialect) greatest() string { return "GREATEST" }
+
+func (postgresDialect) forUpdate() string { return " FOR UPDATE" }
+
+func (postgresDialect) hourOf(column string) string {
+	return "to_char(" + column + ", 'YYYY-MM-DD HH24:00:00')"
+}
+
+func (postgresDialect) dateOf(column string) string { return "CAST(" + column + " AS DATE)" }
+
+// DB の種類
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// DB の種類の方言を返す（不明な場合は MySQL）
+func dialectOf(driver string) dialect {
+	switch driver {
+	case DriverPostgres:
+		return postgresDialect{}
+	case DriverSQLite:
+		return sqliteDialect{}
+	}
+	return mysqlDialect{}
+}
+
+// sqlQuerier は *sql.DB と *sql.Tx に共通の操作
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// dialectQuerier は方言に合わせてパラメータを変換してからクエリを実行する
+type dialectQuerier struct {
+	q sqlQuerier
+	d dialect
+}
+
+func (q dialectQuerier) bind(args []interface{}) []interface{} {
+	bound := make([]interface{}, len(args))
+	for i, arg := range args {
+		bound[i] = q.d.bindValue(arg)
+	}
+	return bound
+}
+
+func (q dialectQuerier) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return q.q.ExecContext(context.Background(), q.d.rebind(query), q.bind(args)...)
+}
+
+func (q dialectQuerier) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return q.q.QueryContext(context.Background(), q.d.rebind(query), q.bind(args)...)
+}
+
+func (q dialectQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return q.q.QueryContext(ctx, q.d.rebind(query), q.bind(args)...)
+}
+
+func (q dialectQuerier) QueryRow(query string, args ...interface{}) *sql.Row {
+	return q.q.QueryRowContext(context.Background(), q.d.rebind(query), q.bind(args)...)
+}
+
+// sqlTime は日時の列を time.Time として読み込む（集計関数の結果など、文字列で返る場合も変換する）
+type sqlTime struct {
+	Time *time.Time
+}
+
+// 文字列で返る日時の書式
+var sqlTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+func (t sqlTime) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case time.Time:
+		*t.Time = v.UTC()
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into time", src)
+	}
+	for _, layout := range sqlTimeLayouts {
+		if parsed, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			*t.Time = parsed.UTC()
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as time", s)
+}
@@ -0,0 +1,129 @@
+package infra
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/width"
+)
+
+// 日本郵便の郵便番号データ（KEN_ALL 形式）の列
+const (
+	kenAllJISCode        = 0
+	kenAllPostalCode     = 2
+	kenAllPrefectureKana = 3
+	kenAllCityKana       = 4
+	kenAllTownKana       = 5
+	kenAllPrefecture     = 6
+	kenAllCity           = 7
+	kenAllTown           = 8
+	kenAllColumns        = 15
+)
+
+// 町域が掲載されていない場合の表記
+const kenAllNoTown = "以下に掲載がない場合"
+
+// 月次の差分ファイル名（例: ADD_2401.CSV, DEL_2401.CSV）
+var kenAllDiffFilePattern = regexp.MustCompile(`(?i)^(?:utf_)?(add|del)_(\d{2})(\d{2})\.csv$`)
+
+// KenAllDiffFile は月次の追加・削除データのファイル
+type KenAllDiffFile struct {
+	Version string // YYYY-MM
+	Added   bool   // true: ADD（追加）, false: DEL（削除）
+	Path    string
+}
+
+// ディレクトリ内の月次差分ファイルをバージョン順に返す
+func ListKenAllDiffFiles(dir string) ([]KenAllDiffFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read diff directory: %w", err)
+	}
+
+	var files []KenAllDiffFile
+	for _, entry := range entries {
+		m := kenAllDiffFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		files = append(files, KenAllDiffFile{
+			Version: "20" + m[2] + "-" + m[3],
+			Added:   strings.EqualFold(m[1], "add"),
+			Path:    filepath.Join(dir, entry.Name()),
+		})
+	}
+
+	// 同じ月は削除データを先に適用する
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Version != files[j].Version {
+			return files[i].Version < files[j].Version
+		}
+		return !files[i].Added && files[j].Added
+	})
+	return files, nil
+}
+
+// KEN_ALL 形式のファイルを読み込む（Shift_JIS と UTF-8 に対応）
+func LoadKenAll(path string) ([]entity.PostalRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open KEN_ALL file: %w", err)
+	}
+	return ReadKenAll(data)
+}
+
+// KEN_ALL 形式のデータを PostalRecord に変換
+func ReadKenAll(data []byte) ([]entity.PostalRecord, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if !utf8.Valid(data) {
+		// 日本郵便の配布ファイルは Shift_JIS
+		r = transform.NewReader(r, japanese.ShiftJIS.NewDecoder())
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	var records []entity.PostalRecord
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read KEN_ALL data at line %d: %w", line, err)
+		}
+		if len(row) < kenAllColumns {
+			return nil, fmt.Errorf("invalid KEN_ALL row at line %d: expected %d columns, got %d", line, kenAllColumns, len(row))
+		}
+
+		town, townKana := row[kenAllTown], row[kenAllTownKana]
+		if town == kenAllNoTown {
+			town, townKana = "", ""
+		}
+
+		records = append(records, entity.PostalRecord{
+			PostalCode:     strings.TrimSpace(row[kenAllPostalCode]),
+			JISCode:        strings.TrimSpace(row[kenAllJISCode]),
+			Prefecture:     row[kenAllPrefecture],
+			PrefectureKana: width.Widen.String(row[kenAllPrefectureKana]),
+			City:           row[kenAllCity],
+			CityKana:       width.Widen.String(row[kenAllCityKana]),
+			Town:           town,
+			TownKana:       width.Widen.String(townKana),
+		})
+	}
+
+	return records, nil
+}
@@ -0,0 +1,421 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DB の種類ごとのマイグレーション（migrations/<DB の種類>/<番号>_<名前>.up.sql と .down.sql）
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// DB に適用済みのバージョンがこのバイナリのマイグレーションにない場合のエラー（新しいバージョンで移行した DB など）
+var ErrUnknownSchemaVersion = errors.New("unknown schema version")
+
+// マイグレーションのロックを待つ時間
+const migrationLockTimeout = 60 * time.Second
+
+// Migration は 1 つのバージョンのスキーマ変更
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus はマイグレーションの適用状況
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time // 未適用の場合はゼロ値
+	Unknown   bool      // DB に適用済みだがこのバイナリにない
+}
+
+// DB の種類のマイグレーションをバージョンの順に返す
+func LoadMigrations(driver string) ([]Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s: %w", driver, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		number, label, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+
+		b, err := fs.ReadFile(migrationFiles, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// 未適用のマイグレーションをすべて適用し、適用したマイグレーションを返す
+// DB に適用済みのバージョンがこのバイナリにない場合は何もせず ErrUnknownSchemaVersion を返す
+func (m *DBManager) MigrateUp(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withMigrationLock(ctx, func(conn *sql.Conn, migrations []Migration, appliedAt map[int]time.Time) error {
+		if err := checkKnownVersions(migrations, appliedAt); err != nil {
+			return err
+		}
+		if m.Driver == DriverMySQL {
+			_, initialApplied := appliedAt[initialMigrationVersion]
+			if err := m.upgradeLegacySchema(ctx, conn, initialApplied); err != nil {
+				return err
+			}
+		}
+		for _, migration := range migrations {
+			if _, ok := appliedAt[migration.Version]; ok {
+				continue
+			}
+			if err := m.applyMigration(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// 適用済みのマイグレーションを新しい順に steps 個戻し、戻したマイグレーションを返す
+func (m *DBManager) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withMigrationLock(ctx, func(conn *sql.Conn, migrations []Migration, appliedAt map[int]time.Time) error {
+		if err := checkKnownVersions(migrations, appliedAt); err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			if _, ok := appliedAt[migrations[i].Version]; !ok {
+				continue
+			}
+			if err := m.applyMigration(ctx, conn, migrations[i], false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migrations[i])
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// マイグレーションの適用状況をバージョンの順に返す（このバイナリにないバージョンも含める）
+// 読み取りだけのため、ロックを取らず実行中の移行も待たない
+func (m *DBManager) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(m.Driver)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := m.schemaMigrationsExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[int]time.Time)
+	if exists {
+		if appliedAt, err = appliedVersions(ctx, m.DB); err != nil {
+			return nil, err
+		}
+	}
+	return migrationStatuses(migrations, appliedAt), nil
+}
+
+// マイグレーションと適用済みのバージョンから適用状況をバージョンの順に作る
+func migrationStatuses(migrations []Migration, appliedAt map[int]time.Time) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	known := make(map[int]struct{}, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = struct{}{}
+		at, ok := appliedAt[migration.Version]
+		statuses = append(statuses, MigrationStatus{Version: migration.Version, Name: migration.Name, Applied: ok, AppliedAt: at})
+	}
+	for version, at := range appliedAt {
+		if _, ok := known[version]; !ok {
+			statuses = append(statuses, MigrationStatus{Version: version, Applied: true, AppliedAt: at, Unknown: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
+
+// DB に適用済みのバージョンがすべてこのバイナリのマイグレーションにあるかを確認
+func checkKnownVersions(migrations []Migration, appliedAt map[int]time.Time) error {
+	known := make(map[int]struct{}, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = struct{}{}
+	}
+	var unknown []int
+	for version := range appliedAt {
+		if _, ok := known[version]; !ok {
+			unknown = append(unknown, version)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Ints(unknown)
+		return fmt.Errorf("%w: %v (this binary knows up to %d)", ErrUnknownSchemaVersion, unknown, migrations[len(migrations)-1].Version)
+	}
+	return nil
+}
+
+// 他のプロセスと同時に移行しないようロックを取り、マイグレーションと適用済みのバージョンを fn に渡す
+// ロックは接続ごとのため、fn では渡した接続だけを使う
+func (m *DBManager) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn, migrations []Migration, appliedAt map[int]time.Time) error) error {
+	migrations, err := LoadMigrations(m.Driver)
+	if err != nil {
+		return err
+	}
+
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection for migrations: %w", err)
+	}
+	defer conn.Close()
+
+	if err := m.lockMigrations(ctx, conn); err != nil {
+		return err
+	}
+	defer m.unlockMigrations(conn)
+
+	if _, err := conn.ExecContext(ctx, m.schemaMigrationsTable()); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	appliedAt, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, migrations, appliedAt)
+}
+
+// マイグレーションのロックを取る（MySQL は名前付きロック、PostgreSQL はアドバイザリーロック）
+// SQLite のファイルは 1 つのプロセスだけが使うため、ロックは取らない
+func (m *DBManager) lockMigrations(ctx context.Context, conn *sql.Conn) error {
+	switch m.Driver {
+	case DriverSQLite:
+	case DriverPostgres:
+		ctx, cancel := context.WithTimeout(ctx, migrationLockTimeout)
+		defer cancel()
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+	default:
+		var acquired sql.NullInt64
+		query := "SELECT GET_LOCK(?, ?)"
+		if err := conn.QueryRowContext(ctx, query, migrationLockName, int(migrationLockTimeout.Seconds())).Scan(&acquired); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if acquired.Int64 != 1 {
+			return fmt.Errorf("failed to acquire migration lock: another migration is running")
+		}
+	}
+	return nil
+}
+
+// マイグレーションのロックを解放する
+func (m *DBManager) unlockMigrations(conn *sql.Conn) {
+	var err error
+	switch m.Driver {
+	case DriverSQLite:
+		return
+	case DriverPostgres:
+		_, err = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	default:
+		_, err = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationLockName)
+	}
+	if err != nil {
+		fmt.Printf("Failed to release migration lock: %v\n", err)
+	}
+}
+
+// マイグレーションのロックの名前（MySQL）とキー（PostgreSQL）
+const (
+	migrationLockName = "finatext_schema_migrations"
+	migrationLockKey  = 7274135226173546000
+)
+
+// 適用済みのバージョンを記録するテーブル
+func (m *DBManager) schemaMigrationsTable() string {
+	if m.Driver == DriverPostgres {
+		return `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`
+	}
+	return `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		applied_at DATETIME NOT NULL,
+		PRIMARY KEY (version)
+	)`
+}
+
+// schema_migrations があるかを確認する
+func (m *DBManager) schemaMigrationsExists(ctx context.Context) (bool, error) {
+	var query string
+	switch m.Driver {
+	case DriverPostgres:
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'"
+	case DriverSQLite:
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'"
+	default:
+		query = "SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'schema_migrations'"
+	}
+	var count int
+	if err := m.DB.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+	return count > 0, nil
+}
+
+// 適用済みのバージョンと適用日時
+func appliedVersions(ctx context.Context, q sqlQuerier) (map[int]time.Time, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schema versions: %w", err)
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, sqlTime{&at}); err != nil {
+			return nil, fmt.Errorf("failed to scan schema version: %w", err)
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over schema versions: %w", err)
+	}
+	return appliedAt, nil
+}
+
+// access_logs を作成する最初のマイグレーションのバージョン
+const initialMigrationVersion = 1
+
+// 最初のマイグレーションの down で access_logs を削除する文
+const dropAccessLogsStatement = "DROP TABLE IF EXISTS access_logs"
+
+// マイグレーションを適用（up が false の場合は戻す）し、schema_migrations に記録する
+// PostgreSQL では DDL もトランザクションで戻せるが、MySQL の DDL は途中で失敗すると適用済みの文が残る
+func (m *DBManager) applyMigration(ctx context.Context, conn *sql.Conn, migration Migration, up bool) (err error) {
+	script, action, done := migration.Up, "apply", "applied"
+	if !up {
+		script, action, done = migration.Down, "revert", "reverted"
+	}
+
+	keepLegacy := false
+	if !up && m.Driver == DriverMySQL && migration.Version == initialMigrationVersion {
+		if keepLegacy, err = isLegacyAccessLogs(ctx, conn); err != nil {
+			return err
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, statement := range splitStatements(script) {
+		// マイグレーションの導入前からある access_logs は最初のマイグレーションを戻しても残す
+		if keepLegacy && statement == dropAccessLogsStatement {
+			fmt.Println("Keeping access_logs created before schema migrations.")
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to %s migration %d_%s: %w", action, migration.Version, migration.Name, err)
+		}
+	}
+
+	q := dialectQuerier{q: tx, d: dialectOf(m.Driver)}
+	if up {
+		_, err = q.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", migration.Version, migration.Name, time.Now().UTC())
+	} else {
+		_, err = q.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	fmt.Printf("Migration %d_%s %s.\n", migration.Version, migration.Name, done)
+	return nil
+}
+
+// SQL のスクリプトを文ごとに分ける（-- のコメント行は除き、行末の ; で区切る）
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
@@ -0,0 +1,183 @@
+package infra_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	var versions [][]int
+	for _, driver := range []string{infra.DriverMySQL, infra.DriverPostgres, infra.DriverSQLite} {
+		migrations, err := infra.LoadMigrations(driver)
+		require.NoError(t, err, driver)
+		require.NotEmpty(t, migrations, driver)
+
+		var driverVersions []int
+		for i, m := range migrations {
+			// 番号は 1 から連番
+			assert.Equal(t, i+1, m.Version, driver)
+			assert.NotEmpty(t, m.Name, driver)
+			assert.Contains(t, m.Up, "CREATE TABLE", driver)
+			assert.Contains(t, m.Down, "DROP TABLE", driver)
+			driverVersions = append(driverVersions, m.Version)
+		}
+		versions = append(versions, driverVersions)
+	}
+
+	// すべての DB で同じバージョンまで揃える
+	assert.Equal(t, versions[0], versions[1])
+	assert.Equal(t, versions[0], versions[2])
+}
+
+func TestLoadMigrationsUnknownDriver(t *testing.T) {
+	_, err := infra.LoadMigrations("oracle")
+	assert.Error(t, err)
+}
+
+// 適用・状態の確認・戻しを実際の DB で確認する（TEST_MYSQL_DSN・TEST_POSTGRES_DSN を指定した場合のみ）
+func TestMigrateUpDown(t *testing.T) {
+	for driver, env := range map[string]string{infra.DriverMySQL: "TEST_MYSQL_DSN", infra.DriverPostgres: "TEST_POSTGRES_DSN"} {
+		driver, env := driver, env
+		t.Run(driver, func(t *testing.T) {
+			dsn := os.Getenv(env)
+			if dsn == "" {
+				t.Skipf("%s is not set", env)
+			}
+			dbManager, err := infra.NewDBManager(driver, dsn, infra.DBOptions{})
+			require.NoError(t, err)
+			t.Cleanup(func() { dbManager.DB.Close() })
+			testMigrateUpDown(t, dbManager)
+		})
+	}
+}
+
+// 適用・状態の確認・戻しを SQLite のファイルで確認する
+func TestMigrateUpDownSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrate.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	dbManager := &infra.DBManager{DB: db, Driver: infra.DriverSQLite}
+
+	// 状態の確認だけでは schema_migrations を作成しない
+	statuses, err := dbManager.MigrationStatus(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+	for _, status := range statuses {
+		assert.False(t, status.Applied)
+	}
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations'").Scan(&count))
+	assert.Zero(t, count)
+
+	testMigrateUpDown(t, dbManager)
+}
+
+func testMigrateUpDown(t *testing.T, dbManager *infra.DBManager) {
+	ctx := context.Background()
+	migrations, err := infra.LoadMigrations(dbManager.Driver)
+	require.NoError(t, err)
+
+	_, err = dbManager.MigrateUp(ctx)
+	require.NoError(t, err)
+	statuses, err := dbManager.MigrationStatus(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(migrations))
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+		assert.False(t, status.AppliedAt.IsZero())
+	}
+
+	// 適用済みの場合は何もしない
+	applied, err := dbManager.MigrateUp(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := dbManager.MigrateDown(ctx, len(migrations))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(migrations))
+	statuses, err = dbManager.MigrationStatus(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.False(t, status.Applied)
+	}
+
+	// 知らないバージョンが適用済みの場合は適用も戻しもしない
+	_, err = dbManager.DB.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'future', CURRENT_TIMESTAMP)")
+	require.NoError(t, err)
+	t.Cleanup(func() { dbManager.DB.Exec("DELETE FROM schema_migrations WHERE version = 9999") })
+	_, err = dbManager.MigrateUp(ctx)
+	assert.ErrorIs(t, err, infra.ErrUnknownSchemaVersion)
+	_, err = dbManager.MigrateDown(ctx, 1)
+	assert.ErrorIs(t, err, infra.ErrUnknownSchemaVersion)
+	statuses, err = dbManager.MigrationStatus(ctx)
+	require.NoError(t, err)
+	if assert.Len(t, statuses, len(migrations)+1) {
+		last := statuses[len(statuses)-1]
+		assert.Equal(t, 9999, last.Version)
+		assert.True(t, last.Applied)
+		assert.True(t, last.Unknown)
+	}
+
+	_, err = dbManager.DB.Exec("DELETE FROM schema_migrations WHERE version = 9999")
+	require.NoError(t, err)
+	_, err = dbManager.MigrateUp(ctx)
+	require.NoError(t, err)
+}
+
+// マイグレーションの導入前に作成した access_logs を MySQL で確認する（TEST_MYSQL_DSN を指定した場合のみ）
+func TestMigrateLegacyAccessLogsMySQL(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+	dbManager, err := infra.NewDBManager(infra.DriverMySQL, dsn, infra.DBOptions{})
+	require.NoError(t, err)
+	t.Cleanup(func() { dbManager.DB.Close() })
+	ctx := context.Background()
+
+	migrations, err := infra.LoadMigrations(infra.DriverMySQL)
+	require.NoError(t, err)
+	_, err = dbManager.MigrateUp(ctx)
+	require.NoError(t, err)
+	_, err = dbManager.MigrateDown(ctx, len(migrations))
+	require.NoError(t, err)
+	_, err = dbManager.DB.Exec("DROP TABLE IF EXISTS schema_migrations")
+	require.NoError(t, err)
+	_, err = dbManager.DB.Exec(`CREATE TABLE access_logs (
+		id INT AUTO_INCREMENT NOT NULL,
+		postal_code VARCHAR(8) NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (id)
+	)`)
+	require.NoError(t, err)
+	_, err = dbManager.DB.Exec("INSERT INTO access_logs (postal_code, created_at) VALUES ('1020073', UTC_TIMESTAMP())")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		dbManager.DB.Exec("DROP TABLE IF EXISTS access_logs")
+		dbManager.MigrateUp(ctx)
+	})
+
+	// migrate up でも足りない列を追加する
+	_, err = dbManager.MigrateUp(ctx)
+	require.NoError(t, err)
+	var columns int
+	require.NoError(t, dbManager.DB.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'access_logs' AND COLUMN_NAME IN ('entry_id', 'client_id')
+	`).Scan(&columns))
+	assert.Equal(t, 2, columns)
+
+	// 最初のマイグレーションを戻しても既存のアクセスログは残す
+	_, err = dbManager.MigrateDown(ctx, len(migrations))
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, dbManager.DB.QueryRow("SELECT COUNT(*) FROM access_logs").Scan(&count))
+	assert.Equal(t, 1, count)
+}
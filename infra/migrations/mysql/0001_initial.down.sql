@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS postal_code_successions;
+DROP TABLE IF EXISTS postal_code_changes;
+DROP TABLE IF EXISTS postal_addresses;
+DROP TABLE IF EXISTS postal_dataset_versions;
+DROP TABLE IF EXISTS access_log_unique_clients;
+DROP TABLE IF EXISTS access_log_counters;
+DROP TABLE IF EXISTS access_log_daily_rollups;
+-- マイグレーションの導入前からある access_logs は削除しない（applyMigration で除く）
+DROP TABLE IF EXISTS access_logs;
@@ -0,0 +1,100 @@
+-- アクセスログ（日時は UTC）
+CREATE TABLE IF NOT EXISTS access_logs (
+	id INT AUTO_INCREMENT NOT NULL,
+	postal_code VARCHAR(8) NOT NULL,
+	status SMALLINT NOT NULL DEFAULT 0,
+	latency_ms DOUBLE NOT NULL DEFAULT 0,
+	client_ip VARCHAR(45) NOT NULL DEFAULT '',
+	user_agent VARCHAR(512) NOT NULL DEFAULT '',
+	request_id VARCHAR(64) NOT NULL DEFAULT '',
+	hit_count INT NOT NULL DEFAULT 0,
+	error_class VARCHAR(32) NOT NULL DEFAULT '',
+	prefecture_code VARCHAR(2) NOT NULL DEFAULT '',
+	city_code VARCHAR(5) NOT NULL DEFAULT '',
+	client_id CHAR(32) NOT NULL DEFAULT '',
+	entry_id CHAR(32) NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (id),
+	UNIQUE INDEX idx_access_logs_entry_id (entry_id),
+	INDEX idx_access_logs_created_at (created_at)
+);
+
+-- 保持期間を過ぎたアクセスログの日次集約（日付は UTC）
+CREATE TABLE IF NOT EXISTS access_log_daily_rollups (
+	day DATE NOT NULL,
+	postal_code VARCHAR(8) NOT NULL,
+	request_count INT NOT NULL,
+	first_seen DATETIME NOT NULL,
+	last_seen DATETIME NOT NULL,
+	PRIMARY KEY (day, postal_code),
+	INDEX idx_access_log_daily_rollups_postal_code (postal_code)
+);
+
+-- 郵便番号ごとのアクセス数のカウンター（アクセスログの保存と同じトランザクションで加算する）
+CREATE TABLE IF NOT EXISTS access_log_counters (
+	postal_code VARCHAR(8) NOT NULL,
+	request_count BIGINT NOT NULL,
+	first_seen DATETIME NOT NULL,
+	last_seen DATETIME NOT NULL,
+	PRIMARY KEY (postal_code),
+	INDEX idx_access_log_counters_request_count (request_count),
+	INDEX idx_access_log_counters_last_seen (last_seen)
+);
+
+-- 郵便番号ごと・日ごと（UTC）のクライアントの HyperLogLog
+CREATE TABLE IF NOT EXISTS access_log_unique_clients (
+	day DATE NOT NULL,
+	postal_code VARCHAR(8) NOT NULL,
+	sketch VARBINARY(1024) NOT NULL,
+	PRIMARY KEY (day, postal_code),
+	INDEX idx_access_log_unique_clients_postal_code (postal_code, day)
+);
+
+-- 郵便番号データセットのバージョン・現在のデータ・変更履歴・廃止された郵便番号の後継
+CREATE TABLE IF NOT EXISTS postal_dataset_versions (
+	id INT AUTO_INCREMENT NOT NULL,
+	version CHAR(7) NOT NULL,
+	kind VARCHAR(8) NOT NULL,
+	source VARCHAR(255) NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	record_count INT NOT NULL,
+	imported_at DATETIME NOT NULL,
+	PRIMARY KEY (id),
+	INDEX idx_postal_dataset_versions_version (version)
+);
+
+CREATE TABLE IF NOT EXISTS postal_addresses (
+	id INT AUTO_INCREMENT NOT NULL,
+	postal_code VARCHAR(8) NOT NULL,
+	jis_code CHAR(5) NOT NULL DEFAULT '',
+	prefecture VARCHAR(16) NOT NULL,
+	prefecture_kana VARCHAR(32) NOT NULL DEFAULT '',
+	city VARCHAR(64) NOT NULL,
+	city_kana VARCHAR(128) NOT NULL DEFAULT '',
+	town VARCHAR(255) NOT NULL DEFAULT '',
+	town_kana VARCHAR(255) NOT NULL DEFAULT '',
+	lat DOUBLE NOT NULL DEFAULT 0,
+	lon DOUBLE NOT NULL DEFAULT 0,
+	PRIMARY KEY (id),
+	INDEX idx_postal_addresses_postal_code (postal_code)
+);
+
+CREATE TABLE IF NOT EXISTS postal_code_changes (
+	id INT AUTO_INCREMENT NOT NULL,
+	version CHAR(7) NOT NULL,
+	postal_code VARCHAR(8) NOT NULL,
+	change_type VARCHAR(8) NOT NULL,
+	old_addresses TEXT NOT NULL,
+	new_addresses TEXT NOT NULL,
+	PRIMARY KEY (id),
+	INDEX idx_postal_code_changes_version (version)
+);
+
+CREATE TABLE IF NOT EXISTS postal_code_successions (
+	id INT AUTO_INCREMENT NOT NULL,
+	version CHAR(7) NOT NULL,
+	retired_postal_code VARCHAR(8) NOT NULL,
+	successor_postal_code VARCHAR(8) NOT NULL,
+	PRIMARY KEY (id),
+	INDEX idx_postal_code_successions_retired (retired_postal_code)
+);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS postal_code_successions;
+DROP TABLE IF EXISTS postal_code_changes;
+DROP TABLE IF EXISTS postal_addresses;
+DROP TABLE IF EXISTS postal_dataset_versions;
+DROP TABLE IF EXISTS access_log_unique_clients;
+DROP TABLE IF EXISTS access_log_counters;
+DROP TABLE IF EXISTS access_log_daily_rollups;
+DROP TABLE IF EXISTS access_logs;
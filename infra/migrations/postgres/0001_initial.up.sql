@@ -0,0 +1,90 @@
+-- アクセスログ（日時は UTC。CHAR は空白で埋められるため、文字列はすべて VARCHAR にする）
+CREATE TABLE IF NOT EXISTS access_logs (
+	id BIGSERIAL PRIMARY KEY,
+	postal_code VARCHAR(8) NOT NULL,
+	status SMALLINT NOT NULL DEFAULT 0,
+	latency_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+	client_ip VARCHAR(45) NOT NULL DEFAULT '',
+	user_agent VARCHAR(512) NOT NULL DEFAULT '',
+	request_id VARCHAR(64) NOT NULL DEFAULT '',
+	hit_count INT NOT NULL DEFAULT 0,
+	error_class VARCHAR(32) NOT NULL DEFAULT '',
+	prefecture_code VARCHAR(2) NOT NULL DEFAULT '',
+	city_code VARCHAR(5) NOT NULL DEFAULT '',
+	client_id VARCHAR(32) NOT NULL DEFAULT '',
+	entry_id VARCHAR(32) NULL UNIQUE,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_access_logs_created_at ON access_logs (created_at);
+
+-- 保持期間を過ぎたアクセスログの日次集約（日付は UTC）
+CREATE TABLE IF NOT EXISTS access_log_daily_rollups (
+	day DATE NOT NULL,
+	postal_code VARCHAR(8) NOT NULL,
+	request_count INT NOT NULL,
+	first_seen TIMESTAMP NOT NULL,
+	last_seen TIMESTAMP NOT NULL,
+	PRIMARY KEY (day, postal_code)
+);
+CREATE INDEX IF NOT EXISTS idx_access_log_daily_rollups_postal_code ON access_log_daily_rollups (postal_code);
+
+-- 郵便番号ごとのアクセス数のカウンター（アクセスログの保存と同じトランザクションで加算する）
+CREATE TABLE IF NOT EXISTS access_log_counters (
+	postal_code VARCHAR(8) PRIMARY KEY,
+	request_count BIGINT NOT NULL,
+	first_seen TIMESTAMP NOT NULL,
+	last_seen TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_access_log_counters_request_count ON access_log_counters (request_count);
+CREATE INDEX IF NOT EXISTS idx_access_log_counters_last_seen ON access_log_counters (last_seen);
+
+-- 郵便番号ごと・日ごと（UTC）のクライアントの HyperLogLog
+CREATE TABLE IF NOT EXISTS access_log_unique_clients (
+	day DATE NOT NULL,
+	postal_code VARCHAR(8) NOT NULL,
+	sketch BYTEA NOT NULL,
+	PRIMARY KEY (day, postal_code)
+);
+CREATE INDEX IF NOT EXISTS idx_access_log_unique_clients_postal_code ON access_log_unique_clients (postal_code, day);
+
+-- 郵便番号データセットのバージョン・現在のデータ・変更履歴・廃止された郵便番号の後継
+CREATE TABLE IF NOT EXISTS postal_dataset_versions (
+	id SERIAL PRIMARY KEY,
+	version VARCHAR(7) NOT NULL,
+	kind VARCHAR(8) NOT NULL,
+	source VARCHAR(255) NOT NULL,
+	checksum VARCHAR(64) NOT NULL,
+	record_count INT NOT NULL,
+	imported_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_postal_dataset_versions_version ON postal_dataset_versions (version);
+CREATE TABLE IF NOT EXISTS postal_addresses (
+	id SERIAL PRIMARY KEY,
+	postal_code VARCHAR(8) NOT NULL,
+	jis_code VARCHAR(5) NOT NULL DEFAULT '',
+	prefecture VARCHAR(16) NOT NULL,
+	prefecture_kana VARCHAR(32) NOT NULL DEFAULT '',
+	city VARCHAR(64) NOT NULL,
+	city_kana VARCHAR(128) NOT NULL DEFAULT '',
+	town VARCHAR(255) NOT NULL DEFAULT '',
+	town_kana VARCHAR(255) NOT NULL DEFAULT '',
+	lat DOUBLE PRECISION NOT NULL DEFAULT 0,
+	lon DOUBLE PRECISION NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_postal_addresses_postal_code ON postal_addresses (postal_code);
+CREATE TABLE IF NOT EXISTS postal_code_changes (
+	id SERIAL PRIMARY KEY,
+	version VARCHAR(7) NOT NULL,
+	postal_code VARCHAR(8) NOT NULL,
+	change_type VARCHAR(8) NOT NULL,
+	old_addresses TEXT NOT NULL,
+	new_addresses TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_postal_code_changes_version ON postal_code_changes (version);
+CREATE TABLE IF NOT EXISTS postal_code_successions (
+	id SERIAL PRIMARY KEY,
+	version VARCHAR(7) NOT NULL,
+	retired_postal_code VARCHAR(8) NOT NULL,
+	successor_postal_code VARCHAR(8) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_postal_code_successions_retired ON postal_code_successions (retired_postal_code);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS access_log_unique_clients;
+DROP TABLE IF EXISTS access_log_counters;
+DROP TABLE IF EXISTS access_log_daily_rollups;
+DROP TABLE IF EXISTS access_logs;
@@ -0,0 +1,48 @@
+-- SQLite はアクセスログの保存先としてのみ使う（日時は UTC の文字列で保存する）
+CREATE TABLE IF NOT EXISTS access_logs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	postal_code TEXT NOT NULL,
+	status INTEGER NOT NULL DEFAULT 0,
+	latency_ms REAL NOT NULL DEFAULT 0,
+	client_ip TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	request_id TEXT NOT NULL DEFAULT '',
+	hit_count INTEGER NOT NULL DEFAULT 0,
+	error_class TEXT NOT NULL DEFAULT '',
+	prefecture_code TEXT NOT NULL DEFAULT '',
+	city_code TEXT NOT NULL DEFAULT '',
+	client_id TEXT NOT NULL DEFAULT '',
+	entry_id TEXT NULL UNIQUE,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_access_logs_created_at ON access_logs (created_at);
+
+-- 保持期間を過ぎたアクセスログの日次集約（日付は UTC）
+CREATE TABLE IF NOT EXISTS access_log_daily_rollups (
+	day DATE NOT NULL,
+	postal_code TEXT NOT NULL,
+	request_count INTEGER NOT NULL,
+	first_seen DATETIME NOT NULL,
+	last_seen DATETIME NOT NULL,
+	PRIMARY KEY (day, postal_code)
+);
+CREATE INDEX IF NOT EXISTS idx_access_log_daily_rollups_postal_code ON access_log_daily_rollups (postal_code);
+
+-- 郵便番号ごとのアクセス数
+CREATE TABLE IF NOT EXISTS access_log_counters (
+	postal_code TEXT NOT NULL PRIMARY KEY,
+	request_count INTEGER NOT NULL,
+	first_seen DATETIME NOT NULL,
+	last_seen DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_access_log_counters_request_count ON access_log_counters (request_count);
+CREATE INDEX IF NOT EXISTS idx_access_log_counters_last_seen ON access_log_counters (last_seen);
+
+-- 郵便番号ごと・日ごとのクライアントの HyperLogLog
+CREATE TABLE IF NOT EXISTS access_log_unique_clients (
+	day DATE NOT NULL,
+	postal_code TEXT NOT NULL,
+	sketch BLOB NOT NULL,
+	PRIMARY KEY (day, postal_code)
+);
+CREATE INDEX IF NOT EXISTS idx_access_log_unique_clients_postal_code ON access_log_unique_clients (postal_code, day);
@@ -0,0 +1,127 @@
+package infra
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/util"
+)
+
+// ローカルデータセットの CSV ヘッダー
+const (
+	columnPostalCode     = "postal_code"
+	columnJISCode        = "jis_code"
+	columnPrefecture     = "prefecture"
+	columnPrefectureKana = "prefecture_kana"
+	columnCity           = "city"
+	columnCityKana       = "city_kana"
+	columnTown           = "town"
+	columnTownKana       = "town_kana"
+	columnLat            = "lat"
+	columnLon            = "lon"
+)
+
+// ヘッダー付き CSV ファイルからローカルの郵便番号データセットを読み込む
+func LoadPostalDataset(path string) ([]entity.PostalRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postal dataset: %w", err)
+	}
+	defer f.Close()
+
+	return ReadPostalDataset(f)
+}
+
+// CSV からローカルの郵便番号データセットを読み込む
+func ReadPostalDataset(r io.Reader) ([]entity.PostalRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read postal dataset header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// 先頭の BOM を取り除く
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	if _, ok := columns[columnPostalCode]; !ok {
+		return nil, fmt.Errorf("postal dataset has no %q column", columnPostalCode)
+	}
+
+	var records []entity.PostalRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read postal dataset at line %d: %w", line, err)
+		}
+
+		record, err := parsePostalRecord(row, columns)
+		if err != nil {
+			return nil, fmt.Errorf("invalid postal dataset row at line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// CSV の 1 行を PostalRecord に変換
+func parsePostalRecord(row []string, columns map[string]int) (entity.PostalRecord, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	record := entity.PostalRecord{
+		PostalCode:     strings.ReplaceAll(field(columnPostalCode), "-", ""),
+		JISCode:        field(columnJISCode),
+		Prefecture:     field(columnPrefecture),
+		PrefectureKana: field(columnPrefectureKana),
+		City:           field(columnCity),
+		CityKana:       field(columnCityKana),
+		Town:           field(columnTown),
+		TownKana:       field(columnTownKana),
+	}
+	if record.PostalCode == "" {
+		return record, errors.New("postal_code is empty")
+	}
+
+	// 全国地方公共団体コードは検査数字を除いた 5 桁で保持する
+	if record.JISCode != "" {
+		code, err := util.NormalizeJISCode(record.JISCode)
+		if err != nil {
+			return record, fmt.Errorf("invalid jis_code %q: %w", record.JISCode, err)
+		}
+		record.JISCode = code
+	}
+
+	// 座標は任意（空欄の場合は 0 のまま）
+	var err error
+	if v := field(columnLat); v != "" {
+		if record.Lat, err = strconv.ParseFloat(v, 64); err != nil {
+			return record, fmt.Errorf("invalid lat %q: %w", v, err)
+		}
+	}
+	if v := field(columnLon); v != "" {
+		if record.Lon, err = strconv.ParseFloat(v, 64); err != nil {
+			return record, fmt.Errorf("invalid lon %q: %w", v, err)
+		}
+	}
+
+	return record, nil
+}
@@ -0,0 +1,305 @@
+package infra
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+)
+
+// 一括 INSERT 1 回あたりの行数
+const postalInsertBatchSize = 500
+
+// 郵便番号を指定して読み込むときの IN 句 1 回あたりの郵便番号の数
+const postalLoadBatchSize = 500
+
+type PostalDatasetRepository struct {
+	DB      *sql.DB
+	dialect dialect
+}
+
+// MySQL に保存する PostalDatasetRepository を作成
+func NewPostalDatasetRepository(db *sql.DB) *PostalDatasetRepository {
+	return &PostalDatasetRepository{DB: db, dialect: mysqlDialect{}}
+}
+
+// 方言に合わせてクエリを実行する
+func (r *PostalDatasetRepository) db() dialectQuerier {
+	return dialectQuerier{q: r.DB, d: r.dialect}
+}
+
+// 取り込み済みのバージョンを古い順に返す
+func (r *PostalDatasetRepository) ListVersions() ([]entity.DatasetVersion, error) {
+	query := `
+		SELECT id, version, kind, source, checksum, record_count, imported_at
+		FROM postal_dataset_versions
+		ORDER BY version, id
+	`
+	rows, err := r.db().Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dataset versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []entity.DatasetVersion
+	for rows.Next() {
+		var v entity.DatasetVersion
+		if err := rows.Scan(&v.ID, &v.Version, &v.Kind, &v.Source, &v.Checksum, &v.RecordCount, &v.ImportedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dataset version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over dataset versions: %w", err)
+	}
+	return versions, nil
+}
+
+// 現在の郵便番号データを返す（postalCodes が nil の場合は全件）
+// postalCodes を指定した場合はプレースホルダーの上限を超えないよう分けて読み込むため、郵便番号ごとの順序だけを保つ
+func (r *PostalDatasetRepository) LoadRecords(postalCodes []string) ([]entity.PostalRecord, error) {
+	if postalCodes == nil {
+		return r.loadRecords("", nil)
+	}
+
+	var records []entity.PostalRecord
+	for start := 0; start < len(postalCodes); start += postalLoadBatchSize {
+		end := start + postalLoadBatchSize
+		if end > len(postalCodes) {
+			end = len(postalCodes)
+		}
+		args := make([]interface{}, 0, end-start)
+		for _, code := range postalCodes[start:end] {
+			args = append(args, code)
+		}
+		batch, err := r.loadRecords(" WHERE postal_code IN ("+placeholders(len(args))+")", args)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, batch...)
+	}
+	return records, nil
+}
+
+// 条件に合う郵便番号データを登録順に返す
+func (r *PostalDatasetRepository) loadRecords(where string, args []interface{}) ([]entity.PostalRecord, error) {
+	query := `
+		SELECT postal_code, jis_code, prefecture, prefecture_kana, city, city_kana, town, town_kana, lat, lon
+		FROM postal_addresses
+	` + where + " ORDER BY id"
+
+	rows, err := r.db().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch postal addresses: %w", err)
+	}
+	defer rows.Close()
+
+	var records []entity.PostalRecord
+	for rows.Next() {
+		var rec entity.PostalRecord
+		if err := rows.Scan(&rec.PostalCode, &rec.JISCode, &rec.Prefecture, &rec.PrefectureKana,
+			&rec.City, &rec.CityKana, &rec.Town, &rec.TownKana, &rec.Lat, &rec.Lon); err != nil {
+			return nil, fmt.Errorf("failed to scan postal address: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over postal addresses: %w", err)
+	}
+	return records, nil
+}
+
+// バージョンの記録・データの置き換え・変更履歴と後継の保存を 1 トランザクションで行う
+func (r *PostalDatasetRepository) SaveVersion(update entity.DatasetUpdate) (err error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	q := dialectQuerier{q: tx, d: r.dialect}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// 既存のデータを削除
+	if update.ReplaceAll {
+		if _, err = q.Exec("DELETE FROM postal_addresses"); err != nil {
+			return fmt.Errorf("failed to clear postal addresses: %w", err)
+		}
+	} else {
+		for postalCode := range update.Records {
+			if _, err = q.Exec("DELETE FROM postal_addresses WHERE postal_code = ?", postalCode); err != nil {
+				return fmt.Errorf("failed to delete postal addresses: %w", err)
+			}
+		}
+	}
+
+	// 新しいデータを一括登録
+	var all []entity.PostalRecord
+	for _, list := range update.Records {
+		all = append(all, list...)
+	}
+	for start := 0; start < len(all); start += postalInsertBatchSize {
+		end := start + postalInsertBatchSize
+		if end > len(all) {
+			end = len(all)
+		}
+		if err = insertPostalAddresses(q, all[start:end]); err != nil {
+			return err
+		}
+	}
+
+	// 変更履歴を登録
+	for _, change := range update.Changes {
+		oldAddresses, _ := json.Marshal(change.OldAddresses)
+		newAddresses, _ := json.Marshal(change.NewAddresses)
+		query := `
+			INSERT INTO postal_code_changes (version, postal_code, change_type, old_addresses, new_addresses)
+			VALUES (?, ?, ?, ?, ?)
+		`
+		if _, err = q.Exec(query, change.Version, change.PostalCode, change.ChangeType, string(oldAddresses), string(newAddresses)); err != nil {
+			return fmt.Errorf("failed to insert postal code change: %w", err)
+		}
+	}
+
+	// 廃止された郵便番号の後継を登録
+	for _, succession := range update.Successions {
+		query := `
+			INSERT INTO postal_code_successions (version, retired_postal_code, successor_postal_code)
+			VALUES (?, ?, ?)
+		`
+		if _, err = q.Exec(query, succession.Version, succession.RetiredPostalCode, succession.SuccessorPostalCode); err != nil {
+			return fmt.Errorf("failed to insert postal code succession: %w", err)
+		}
+	}
+
+	// バージョンを記録
+	version := update.Version
+	query := `
+		INSERT INTO postal_dataset_versions (version, kind, source, checksum, record_count, imported_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	if _, err = q.Exec(query, version.Version, version.Kind, version.Source, version.Checksum, version.RecordCount, version.ImportedAt); err != nil {
+		return fmt.Errorf("failed to insert dataset version: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit dataset version: %w", err)
+	}
+	return nil
+}
+
+// 指定したバージョン以降の変更履歴を返す
+func (r *PostalDatasetRepository) ChangesSince(version string) ([]entity.PostalCodeChange, error) {
+	query := `
+		SELECT version, postal_code, change_type, old_addresses, new_addresses
+		FROM postal_code_changes
+		WHERE version >= ?
+		ORDER BY version, postal_code, id
+	`
+	rows, err := r.db().Query(query, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch postal code changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []entity.PostalCodeChange
+	for rows.Next() {
+		var change entity.PostalCodeChange
+		var oldAddresses, newAddresses string
+		if err := rows.Scan(&change.Version, &change.PostalCode, &change.ChangeType, &oldAddresses, &newAddresses); err != nil {
+			return nil, fmt.Errorf("failed to scan postal code change: %w", err)
+		}
+		if err := json.Unmarshal([]byte(oldAddresses), &change.OldAddresses); err != nil {
+			return nil, fmt.Errorf("failed to decode old addresses: %w", err)
+		}
+		if err := json.Unmarshal([]byte(newAddresses), &change.NewAddresses); err != nil {
+			return nil, fmt.Errorf("failed to decode new addresses: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over postal code changes: %w", err)
+	}
+	return changes, nil
+}
+
+// 廃止された郵便番号と後継の対応を古い順にすべて返す
+func (r *PostalDatasetRepository) ListSuccessions() ([]entity.PostalCodeSuccession, error) {
+	query := `
+		SELECT version, retired_postal_code, successor_postal_code
+		FROM postal_code_successions
+		ORDER BY version, id
+	`
+	rows, err := r.db().Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch postal code successions: %w", err)
+	}
+	defer rows.Close()
+
+	var successions []entity.PostalCodeSuccession
+	for rows.Next() {
+		var succession entity.PostalCodeSuccession
+		if err := rows.Scan(&succession.Version, &succession.RetiredPostalCode, &succession.SuccessorPostalCode); err != nil {
+			return nil, fmt.Errorf("failed to scan postal code succession: %w", err)
+		}
+		successions = append(successions, succession)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over postal code successions: %w", err)
+	}
+	return successions, nil
+}
+
+// 郵便番号データを複数行の INSERT でまとめて登録
+func insertPostalAddresses(q dialectQuerier, records []entity.PostalRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	values := make([]string, len(records))
+	args := make([]interface{}, 0, len(records)*10)
+	for i, rec := range records {
+		values[i] = "(" + placeholders(10) + ")"
+		args = append(args, rec.PostalCode, rec.JISCode, rec.Prefecture, rec.PrefectureKana,
+			rec.City, rec.CityKana, rec.Town, rec.TownKana, rec.Lat, rec.Lon)
+	}
+
+	query := `
+		INSERT INTO postal_addresses
+			(postal_code, jis_code, prefecture, prefecture_kana, city, city_kana, town, town_kana, lat, lon)
+		VALUES ` + strings.Join(values, ", ")
+	if _, err := q.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to insert postal addresses: %w", err)
+	}
+	return nil
+}
+
+// n 個のプレースホルダーをカンマ区切りで返す
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// ファイルの SHA-256 チェックサムを返す
+func FileChecksum(paths ...string) (string, error) {
+	h := sha256.New()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
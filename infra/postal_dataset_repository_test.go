@@ -0,0 +1,70 @@
+package infra_test
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostalDatasetRepositoryLoadRecordsInBatches(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "postal.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`
+		CREATE TABLE postal_addresses (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			postal_code TEXT NOT NULL,
+			jis_code TEXT NOT NULL DEFAULT '',
+			prefecture TEXT NOT NULL,
+			prefecture_kana TEXT NOT NULL DEFAULT '',
+			city TEXT NOT NULL,
+			city_kana TEXT NOT NULL DEFAULT '',
+			town TEXT NOT NULL DEFAULT '',
+			town_kana TEXT NOT NULL DEFAULT '',
+			lat REAL NOT NULL DEFAULT 0,
+			lon REAL NOT NULL DEFAULT 0
+		)`)
+	require.NoError(t, err)
+
+	// 1 回の IN 句に収まらない数の郵便番号（1 つの郵便番号に 2 つの町域）
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	var codes []string
+	for i := 0; i < 1200; i++ {
+		code := fmt.Sprintf("%07d", 1000000+i)
+		codes = append(codes, code)
+		for _, town := range []string{"一丁目", "二丁目"} {
+			_, err := tx.Exec("INSERT INTO postal_addresses (postal_code, prefecture, city, town) VALUES (?, '東京都', '千代田区', ?)", code, town)
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tx.Commit())
+	repo := infra.NewPostalDatasetRepository(db)
+
+	records, err := repo.LoadRecords(codes[100:])
+	require.NoError(t, err)
+	require.Len(t, records, 2200)
+	seen := make(map[string][]string)
+	for _, r := range records {
+		seen[r.PostalCode] = append(seen[r.PostalCode], r.Town)
+	}
+	assert.Len(t, seen, 1100)
+	assert.NotContains(t, seen, codes[99])
+	// 郵便番号ごとの登録順を保つ
+	for _, code := range codes[100:] {
+		assert.Equal(t, []string{"一丁目", "二丁目"}, seen[code], code)
+	}
+
+	records, err = repo.LoadRecords([]string{})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	records, err = repo.LoadRecords(nil)
+	require.NoError(t, err)
+	assert.Len(t, records, 2400)
+}
@@ -0,0 +1,35 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	_ "modernc.org/sqlite" // database/sql の "sqlite" ドライバー
+)
+
+// SQLite のファイルに保存する AccessLogRepository を作成し、未適用のマイグレーションを適用する
+// 読み込みと書き込みを並行できるよう WAL を使い、書き込みのトランザクションは開始時にロックを取る
+func NewSQLiteAccessLogRepository(path string) (*AccessLogRepository, error) {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to sqlite database: %w", err)
+	}
+
+	m := &DBManager{DB: db, Driver: DriverSQLite}
+	if _, err := m.MigrateUp(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return m.AccessLogRepository(), nil
+}
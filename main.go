@@ -3,11 +3,20 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 
 	"github.com/dkpcb/finatext_kadai_2/server"
 )
 
 func main() {
+	// 引数がある場合は管理用のコマンドを実行
+	if len(os.Args) > 1 {
+		if err := server.RunCommand(context.Background(), os.Args[1:]); err != nil {
+			log.Fatalf("failed to run command: %v", err)
+		}
+		return
+	}
+
 	if err := server.Run(context.Background()); err != nil {
 		log.Fatalf("failed to run server: %v", err)
 	}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/dkpcb/finatext_kadai_2/service"
+)
+
+// コマンドの使い方
+const commandUsage = `usage:
+  counters check    カウンターとアクセスログの件数を比較する
+  counters rebuild  カウンターをアクセスログから作り直す
+  export [-format csv|ndjson] [-from 日時] [-to 日時] [-o ファイル]
+                    生のアクセスログを書き出す（-o を省略した場合は標準出力）
+  migrate up        未適用のマイグレーションをすべて適用する
+  migrate down [n]  適用済みのマイグレーションを新しい順に n 個（既定 1）戻す
+  migrate status    マイグレーションの適用状況を表示する`
+
+// RunCommand は管理用のサブコマンドを実行する
+func RunCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command given\n%s", commandUsage)
+	}
+
+	switch args[0] {
+	case "counters":
+		return runCountersCommand(args[1:], os.Stdout)
+	case "export":
+		return runExportCommand(ctx, args[1:], os.Stdout)
+	case "migrate":
+		return runMigrateCommand(ctx, args[1:], os.Stdout)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], commandUsage)
+	}
+}
+
+// アクセスログのカウンターの検査・再構築を実行
+func runCountersCommand(args []string, out io.Writer) error {
+	if len(args) != 1 || (args[0] != "check" && args[0] != "rebuild") {
+		return fmt.Errorf("usage: counters check|rebuild")
+	}
+
+	cfg, err := initializeConfig()
+	if err != nil {
+		return fmt.Errorf("failed to initialize config: %w", err)
+	}
+	dbManager, err := initializeDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer dbManager.DB.Close()
+
+	accessLogRepo, err := initializeAccessLogRepository(cfg, dbManager)
+	if err != nil {
+		return err
+	}
+	accessLogService := service.NewAccessLogService(accessLogRepo)
+
+	if args[0] == "rebuild" {
+		if err := accessLogService.RebuildCounters(); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "access log counters rebuilt")
+		return nil
+	}
+
+	mismatches, err := accessLogService.CheckCounters()
+	if err != nil {
+		return err
+	}
+	for _, m := range mismatches {
+		fmt.Fprintf(out, "%s\tcounted=%d\tactual=%d\n", m.PostalCode, m.Counted, m.Actual)
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%d postal codes have inconsistent counters (run \"counters rebuild\" to fix)", len(mismatches))
+	}
+	fmt.Fprintln(out, "access log counters are consistent")
+	return nil
+}
+
+// 生のアクセスログをファイルまたは stdout に書き出す
+func runExportCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", service.ExportCSV, "csv または ndjson")
+	fromParam := fs.String("from", "", "開始日時（RFC 3339 または YYYY-MM-DD）")
+	toParam := fs.String("to", "", "終了日時（RFC 3339 または YYYY-MM-DD、日付の場合はその日を含む）")
+	output := fs.String("o", "", "出力先のファイル")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !service.IsExportFormat(*format) {
+		return fmt.Errorf("format must be csv or ndjson: %q", *format)
+	}
+
+	cfg, err := initializeConfig()
+	if err != nil {
+		return fmt.Errorf("failed to initialize config: %w", err)
+	}
+	from, err := parseCommandTime(*fromParam, false, cfg.TimeZone)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	to, err := parseCommandTime(*toParam, true, cfg.TimeZone)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+
+	// アクセスログの保存先が DSN の DB の場合だけ接続する
+	var dbManager *infra.DBManager
+	if cfg.AccessLogStore == "db" {
+		if dbManager, err = initializeDatabase(cfg); err != nil {
+			return err
+		}
+		defer dbManager.DB.Close()
+	}
+	accessLogRepo, err := initializeAccessLogRepository(cfg, dbManager)
+	if err != nil {
+		return err
+	}
+
+	out := stdout
+	var file *os.File
+	if *output != "" {
+		if file, err = os.Create(*output); err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	accessLogService := service.NewAccessLogService(accessLogRepo)
+	n, err := accessLogService.ExportAccessLogs(ctx, out, *format, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to export access logs after %d rows: %w", n, err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to close export file: %w", err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "exported %d access logs\n", n)
+	return nil
+}
+
+// スキーマのマイグレーションを実行
+func runMigrateCommand(ctx context.Context, args []string, out io.Writer) error {
+	const usage = "usage: migrate up|down [n]|status"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	steps := 1
+	switch {
+	case args[0] == "down" && len(args) == 2:
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("migrate down: n must be a positive integer: %q", args[1])
+		}
+		steps = n
+	case len(args) != 1 || (args[0] != "up" && args[0] != "down" && args[0] != "status"):
+		return errors.New(usage)
+	}
+
+	cfg, err := initializeConfig()
+	if err != nil {
+		return fmt.Errorf("failed to initialize config: %w", err)
+	}
+	dbManager, err := infra.NewDBManager(cfg.DBDriver(), cfg.DriverDSN(), dbOptions(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to initialize DB manager: %w", err)
+	}
+	defer dbManager.DB.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := dbManager.MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%d migrations applied\n", len(applied))
+	case "down":
+		reverted, err := dbManager.MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%d migrations reverted\n", len(reverted))
+	case "status":
+		statuses, err := dbManager.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			switch {
+			case status.Unknown:
+				state = "unknown (applied by a newer version)"
+			case status.Applied:
+				state = "applied at " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%04d\t%s\t%s\n", status.Version, status.Name, state)
+		}
+	}
+	return nil
+}
+
+// 日時の引数を変換（RFC 3339 の日時、または loc での YYYY-MM-DD の日付）
+// 日付で指定した場合、end が true なら翌日の 0 時（その日を含む）とする
+func parseCommandTime(v string, end bool, loc *time.Location) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
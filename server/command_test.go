@@ -0,0 +1,99 @@
+package server_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/dkpcb/finatext_kadai_2/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SQLite の保存先にアクセスログを保存し、export コマンドの環境変数を設定する
+func setupExportStore(t *testing.T, entries ...entity.AccessLogEntry) {
+	path := filepath.Join(t.TempDir(), "access_logs.db")
+	repo, err := infra.NewSQLiteAccessLogRepository(path)
+	require.NoError(t, err)
+	require.NoError(t, repo.InsertAccessLogs(entries))
+	require.NoError(t, repo.DB.Close())
+
+	t.Setenv("ACCESS_LOG_STORE", "sqlite")
+	t.Setenv("ACCESS_LOG_SQLITE_PATH", path)
+	t.Setenv("TIME_ZONE", "Asia/Tokyo")
+}
+
+func TestRunCommandExport(t *testing.T) {
+	setupExportStore(t,
+		entity.AccessLogEntry{ID: "a1", PostalCode: "1000001", Status: 200, Latency: 2 * time.Millisecond, CreatedAt: time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC)},
+		entity.AccessLogEntry{ID: "a2", PostalCode: "1000002", Status: 404, CreatedAt: time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)},
+	)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+		excluded []string
+	}{
+		{
+			name:     "CSV",
+			args:     []string{"-format", "csv"},
+			expected: []string{"id,postal_code,status,latency_ms,", "a1,1000001,200,2,", "a2,1000002,404,0,"},
+		},
+		{
+			name:     "NDJSON",
+			args:     []string{"-format", "ndjson"},
+			expected: []string{`"id":"a1"`, `"latency_ms":2`, `"id":"a2"`},
+		},
+		{
+			// 日付はタイムゾーン（JST）で解釈し、-to の日を含む
+			name:     "期間を指定",
+			args:     []string{"-from", "2024-01-03", "-to", "2024-01-03"},
+			expected: []string{"a2,1000002,404,"},
+			excluded: []string{"a1,"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "export")
+			err := server.RunCommand(context.Background(), append([]string{"export", "-o", output}, tt.args...))
+			require.NoError(t, err)
+
+			b, err := os.ReadFile(output)
+			require.NoError(t, err)
+			for _, s := range tt.expected {
+				assert.Contains(t, string(b), s)
+			}
+			for _, s := range tt.excluded {
+				assert.NotContains(t, string(b), s)
+			}
+		})
+	}
+}
+
+func TestRunCommandExportInvalidArgs(t *testing.T) {
+	setupExportStore(t)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "形式が不正", args: []string{"-format", "xml"}, expected: "format must be csv or ndjson"},
+		{name: "開始日時が不正", args: []string{"-from", "yesterday"}, expected: "invalid -from"},
+		{name: "終了日時が不正", args: []string{"-to", "2024-13-01"}, expected: "invalid -to"},
+		{name: "不明なフラグ", args: []string{"-limit", "10"}, expected: "flag provided but not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := server.RunCommand(context.Background(), append([]string{"export"}, tt.args...))
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
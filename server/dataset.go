@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/config"
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/dkpcb/finatext_kadai_2/service"
+)
+
+// ローカルデータセットと月次の差分データを DB に取り込み、現在の郵便番号データを返す
+func initializePostalDataset(cfg *config.Config, datasetService *service.DatasetService) ([]entity.PostalRecord, error) {
+	if cfg.PostalDataset != "" {
+		if err := importPostalDataset(cfg, datasetService); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.PostalDiffDir != "" {
+		if err := applyPostalDiffs(cfg.PostalDiffDir, datasetService); err != nil {
+			return nil, err
+		}
+	}
+
+	return datasetService.Snapshot()
+}
+
+// 全件データを取り込む（バージョン未指定の場合はファイルの更新年月）
+func importPostalDataset(cfg *config.Config, datasetService *service.DatasetService) error {
+	version := cfg.PostalDatasetVersion
+	if version == "" {
+		info, err := os.Stat(cfg.PostalDataset)
+		if err != nil {
+			return fmt.Errorf("failed to stat postal dataset: %w", err)
+		}
+		version = info.ModTime().Format("2006-01")
+	}
+	if _, err := time.Parse("2006-01", version); err != nil {
+		return fmt.Errorf("invalid postal dataset version %q: must be YYYY-MM", version)
+	}
+
+	records, err := infra.LoadPostalDataset(cfg.PostalDataset)
+	if err != nil {
+		return err
+	}
+	checksum, err := infra.FileChecksum(cfg.PostalDataset)
+	if err != nil {
+		return err
+	}
+
+	imported, err := datasetService.ImportSnapshot(version, filepath.Base(cfg.PostalDataset), checksum, records)
+	if err != nil {
+		return err
+	}
+	if imported {
+		log.Printf("Imported postal dataset %s (%d records)", version, len(records))
+	}
+	return nil
+}
+
+// 月次の追加・削除データをバージョン順に適用する
+func applyPostalDiffs(dir string, datasetService *service.DatasetService) error {
+	files, err := infra.ListKenAllDiffFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(files); {
+		// 同じ月の ADD / DEL をまとめて 1 バージョンとして適用
+		version := files[start].Version
+		end := start
+		for end < len(files) && files[end].Version == version {
+			end++
+		}
+
+		var added, deleted []entity.PostalRecord
+		var paths, names []string
+		for _, f := range files[start:end] {
+			records, err := infra.LoadKenAll(f.Path)
+			if err != nil {
+				return err
+			}
+			if f.Added {
+				added = append(added, records...)
+			} else {
+				deleted = append(deleted, records...)
+			}
+			paths = append(paths, f.Path)
+			names = append(names, filepath.Base(f.Path))
+		}
+		checksum, err := infra.FileChecksum(paths...)
+		if err != nil {
+			return err
+		}
+
+		applied, err := datasetService.ApplyDiff(version, strings.Join(names, ","), checksum, added, deleted)
+		if err != nil {
+			return err
+		}
+		if applied {
+			log.Printf("Applied postal dataset diff %s (%d added, %d deleted)", version, len(added), len(deleted))
+		}
+		start = end
+	}
+	return nil
+}
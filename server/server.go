@@ -2,17 +2,22 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
 	"github.com/dkpcb/finatext_kadai_2/config"
+	"github.com/dkpcb/finatext_kadai_2/entity"
 	"github.com/dkpcb/finatext_kadai_2/handler"
 	"github.com/dkpcb/finatext_kadai_2/infra"
 	"github.com/dkpcb/finatext_kadai_2/service"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 )
 
 // Run はアプリケーションのエントリーポイント
@@ -31,7 +36,7 @@ func Run(ctx context.Context) error {
 	defer dbManager.DB.Close()
 
 	// サーバーの起動とシャットダウン管理
-	return startServer(ctx, cfg, services)
+	return startServer(ctx, cfg, dbManager, services)
 }
 
 // 設定を初期化
@@ -41,42 +46,168 @@ func initializeConfig() (*config.Config, error) {
 
 // データベースと依存性を初期化
 func initializeDependencies(cfg *config.Config) (*infra.DBManager, *service.ServiceRegistry, error) {
-	// データベース接続を初期化
-	dbManager, err := infra.NewDBManager(cfg.DSN)
+	// データベース接続とスキーマを初期化
+	dbManager, err := initializeDatabase(cfg)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to initialize DB manager: %w", err)
+		return nil, nil, err
 	}
 
-	// スキーマを初期化
-	if err := dbManager.InitializeSchema("finatext_db"); err != nil {
-		return nil, nil, fmt.Errorf("failed to initialize schema: %w", err)
+	// ローカルの郵便番号データセットを DB に取り込み、現在のデータを読み込む
+	datasetService := service.NewDatasetService(dbManager.PostalDatasetRepository())
+	postalRecords, err := initializePostalDataset(cfg, datasetService)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load postal dataset: %w", err)
+	}
+
+	successions, err := datasetService.Successions()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load postal code successions: %w", err)
 	}
 
 	// リポジトリを初期化
 	addressRepo := infra.NewAddressRepository(cfg.ExternalAPI)
-	accessLogRepo := infra.NewAccessLogRepository(dbManager.DB)
+	accessLogRepo, err := initializeAccessLogRepository(cfg, dbManager)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := accessLogRepo.InitializeCounters(); err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize access log counters: %w", err)
+	}
 
 	// サービスを初期化
+	regionService := service.NewRegionService(postalRecords)
+	addressService := service.NewAddressService(addressRepo, cfg.ExternalAPI)
+	addressService.Regions = regionService
+	addressService.Successions = service.NewSuccessionService(successions, postalRecords)
+	accessLogService := service.NewAccessLogService(accessLogRepo)
+	accessLogService.Regions = regionService
+	accessLogService.Trending = service.NewTrendingCounter(cfg.TrendingResolution, cfg.TrendingMaxWindow, cfg.TrendingCapacity)
+	if accessLogService.ClientIDSalt, err = clientIDSalt(cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize client id salt: %w", err)
+	}
+	var accessLogSink service.AccessLogSink = accessLogRepo
+	if cfg.AccessLogSpoolPath != "" {
+		accessLogService.Spool = service.NewSpoolingAccessLogSink(accessLogRepo, infra.NewFileAccessLogSpool(cfg.AccessLogSpoolPath))
+		accessLogSink = accessLogService.Spool
+	}
+	if cfg.AccessLogRetentionDays > 0 {
+		accessLogService.Retention = service.NewAccessLogRetention(accessLogRepo, cfg.AccessLogRetentionDays, cfg.AccessLogPurgeBatchSize)
+	}
+	if cfg.AccessLogAsync {
+		accessLogService.Writer = service.NewAccessLogWriter(accessLogSink, service.AccessLogWriterOptions{
+			BufferSize:    cfg.AccessLogBufferSize,
+			BatchSize:     cfg.AccessLogBatchSize,
+			FlushInterval: cfg.AccessLogFlushInterval,
+			Overflow:      cfg.AccessLogOverflow,
+		})
+	}
 	services := &service.ServiceRegistry{
-		Address:   service.NewAddressService(addressRepo, cfg.ExternalAPI),
-		AccessLog: service.NewAccessLogService(accessLogRepo),
+		Address:   addressService,
+		AccessLog: accessLogService,
+		Distance:  service.NewDistanceService(addressRepo, cfg.MatrixConcurrency, cfg.GeoCacheTTL),
+		Nearby:    service.NewNearbyService(postalRecords),
+		Region:    regionService,
+		Dataset:   datasetService,
 	}
 
 	return dbManager, services, nil
 }
 
+// クライアントの識別子のソルト（未指定の場合はランダムに生成）
+func clientIDSalt(cfg *config.Config) ([]byte, error) {
+	if cfg.ClientIDSalt != "" {
+		return []byte(cfg.ClientIDSalt), nil
+	}
+	log.Printf("CLIENT_ID_SALT is not set; using a random salt, so unique client counts will not be continuous across restarts")
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// データベース接続とスキーマを初期化
+func initializeDatabase(cfg *config.Config) (*infra.DBManager, error) {
+	dbManager, err := infra.NewDBManager(cfg.DBDriver(), cfg.DriverDSN(), dbOptions(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize DB manager: %w", err)
+	}
+
+	// DSN のデータベースにマイグレーションを適用
+	if err := dbManager.InitializeSchema(); err != nil {
+		dbManager.DB.Close()
+		if errors.Is(err, infra.ErrUnknownSchemaVersion) {
+			return nil, fmt.Errorf("refusing to start on a database migrated by a newer version: %w", err)
+		}
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+	return dbManager, nil
+}
+
+// 設定から DB の接続プールと起動時の再試行の設定を作成
+func dbOptions(cfg *config.Config) infra.DBOptions {
+	return infra.DBOptions{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+		ConnectAttempts: cfg.DBConnectAttempts,
+		InitialBackoff:  cfg.DBConnectBackoff,
+		MaxBackoff:      cfg.DBConnectMaxBackoff,
+	}
+}
+
+// 設定に応じてアクセスログの保存先を作成（db の場合は DSN の DB を使う）
+func initializeAccessLogRepository(cfg *config.Config, dbManager *infra.DBManager) (entity.AccessLogRepository, error) {
+	switch cfg.AccessLogStore {
+	case "sqlite":
+		repo, err := infra.NewSQLiteAccessLogRepository(cfg.AccessLogSQLitePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize sqlite access log store: %w", err)
+		}
+		return repo, nil
+	case "memory":
+		log.Printf("ACCESS_LOG_STORE is memory; access logs will be lost on restart")
+		return infra.NewMemoryAccessLogRepository(), nil
+	default:
+		return dbManager.AccessLogRepository(), nil
+	}
+}
+
 // サーバーを起動し、シグナルを監視してグレースフルシャットダウンを実行
-func startServer(ctx context.Context, cfg *config.Config, services *service.ServiceRegistry) error {
+func startServer(ctx context.Context, cfg *config.Config, dbManager *infra.DBManager, services *service.ServiceRegistry) error {
 	e := echo.New()
+	e.Use(middleware.RequestID())
 
 	// ルートを登録
 	h := handler.NewHandler(services.Address, services.AccessLog, cfg)
+	h.DistanceService = services.Distance
+	h.NearbyService = services.Nearby
+	h.RegionService = services.Region
+	h.DatasetService = services.Dataset
+	h.LookupHub = service.NewLookupHub(cfg.LookupStreamBufferSize)
+	h.Readiness = dbManager
 	h.RegisterRoutes(e)
 
+	// シャットダウン時に配信中のライブストリームを閉じる
+	e.Server.RegisterOnShutdown(h.LookupHub.Close)
+
 	// シグナルの監視
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
 	defer stop()
 
+	// DB の疎通を定期的に確認し、/readyz に反映
+	go dbManager.RunHealthCheck(ctx, cfg.DBPingInterval)
+
+	// DB の復旧後にスプールしたアクセスログを再送
+	if services.AccessLog.Spool != nil {
+		go services.AccessLog.Spool.RunReplayer(ctx, cfg.AccessLogReplayInterval)
+	}
+
+	// 保持期間を過ぎたアクセスログを日次に集約してから削除
+	if services.AccessLog.Retention != nil {
+		go services.AccessLog.Retention.Run(ctx, cfg.AccessLogPurgeInterval)
+	}
+
 	// サーバーを非同期で起動
 	go func() {
 		if err := e.Start(cfg.Port); err != nil && err != http.ErrServerClosed {
@@ -90,105 +221,22 @@ func startServer(ctx context.Context, cfg *config.Config, services *service.Serv
 	// グレースフルシャットダウン
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	var errs []error
 	if err := e.Shutdown(shutdownCtx); err != nil {
-		return fmt.Errorf("failed to shut down server: %w", err)
+		errs = append(errs, fmt.Errorf("failed to shut down server: %w", err))
+	}
+
+	// リクエストの処理が終わってから溜まっているアクセスログを書き出す
+	// サーバーの停止に失敗した場合も、HTTP の停止とは別の期限で必ず書き出す
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), cfg.AccessLogCloseTimeout)
+	defer cancelClose()
+	if err := services.AccessLog.Close(closeCtx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to flush access logs: %w", err))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 
 	e.Logger.Info("Server gracefully stopped")
 	return nil
 }
-
-// package server
-
-// import (
-// 	"context"
-// 	"fmt"
-// 	"net/http"
-// 	"os"
-// 	"os/signal"
-// 	"time"
-
-// 	"github.com/dkpcb/finatext_kadai_2/config"
-// 	"github.com/dkpcb/finatext_kadai_2/handler"
-// 	"github.com/dkpcb/finatext_kadai_2/infra"
-// 	"github.com/dkpcb/finatext_kadai_2/service"
-// 	"github.com/labstack/echo/v4"
-// )
-
-// // ConfigLoader は設定をロードする関数
-// type ConfigLoader func() (*config.Config, error)
-
-// // Run はアプリケーションのエントリーポイント
-// func Run(ctx context.Context) error {
-// 	return RunWithMockConfig(ctx, config.New)
-// }
-
-// // RunWithMockConfig は設定ローダーを差し替え可能なエントリーポイント
-// func RunWithMockConfig(ctx context.Context, loadConfig ConfigLoader) error {
-// 	// 設定の初期化
-// 	cfg, err := loadConfig()
-// 	if err != nil {
-// 		return fmt.Errorf("failed to initialize config: %w", err)
-// 	}
-
-// 	// データベースと依存性の初期化
-// 	dbManager, services, err := initializeDependencies(cfg)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to initialize dependencies: %w", err)
-// 	}
-// 	defer dbManager.DB.Close()
-
-// 	// サーバーの起動とシャットダウン管理
-// 	return startServer(ctx, cfg, services)
-// }
-
-// // データベースと依存性を初期化
-// func initializeDependencies(cfg *config.Config) (*infra.DBManager, *service.ServiceRegistry, error) {
-// 	dbManager, err := infra.NewDBManager(cfg.DSN)
-// 	if err != nil {
-// 		return nil, nil, err
-// 	}
-
-// 	addressRepo := infra.NewAddressRepository(cfg.ExternalAPI)
-// 	accessLogRepo := infra.NewAccessLogRepository(dbManager.DB)
-
-// 	services := &service.ServiceRegistry{
-// 		Address:   service.NewAddressService(addressRepo, cfg.ExternalAPI),
-// 		AccessLog: service.NewAccessLogService(accessLogRepo),
-// 	}
-
-// 	return dbManager, services, nil
-// }
-
-// // サーバーを起動し、シグナルを監視してグレースフルシャットダウンを実行
-// func startServer(ctx context.Context, cfg *config.Config, services *service.ServiceRegistry) error {
-// 	e := echo.New()
-
-// 	// ルートを登録
-// 	h := handler.NewHandler(services.Address, services.AccessLog, cfg)
-// 	h.RegisterRoutes(e)
-
-// 	// シグナルの監視
-// 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
-// 	defer stop()
-
-// 	// サーバーを非同期で起動
-// 	go func() {
-// 		if err := e.Start(cfg.Port); err != nil && err != http.ErrServerClosed {
-// 			e.Logger.Fatal("shutting down the server")
-// 		}
-// 	}()
-
-// 	// シグナルを受信するまで待機
-// 	<-ctx.Done()
-
-// 	// グレースフルシャットダウン
-// 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-// 	defer cancel()
-// 	if err := e.Shutdown(shutdownCtx); err != nil {
-// 		return fmt.Errorf("failed to shut down server: %w", err)
-// 	}
-
-// 	e.Logger.Info("Server gracefully stopped")
-// 	return nil
-// }
@@ -2,21 +2,23 @@ package server_test
 
 import (
 	"context"
-	"errors"
+	"os"
 	"testing"
 	"time"
 
-	"github.com/dkpcb/finatext_kadai_2/config"
 	"github.com/dkpcb/finatext_kadai_2/server"
 	"github.com/stretchr/testify/assert"
 )
 
-// MockConfigLoader は config.New をモックする関数
-type MockConfigLoader func() (*config.Config, error)
-
-// TestRun は Run 関数のテスト
+// TestRun は Run 関数のテスト（設定は環境変数で差し替える）
 func TestRun(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
+		// 実際の MySQL に接続するため TEST_MYSQL_DSN を指定した場合のみ（DSN にはデータベース名が必要）
+		dsn := os.Getenv("TEST_MYSQL_DSN")
+		if dsn == "" {
+			t.Skip("TEST_MYSQL_DSN is not set")
+		}
+		t.Setenv("DSN", dsn)
 		ctx, cancel := context.WithCancel(context.Background())
 
 		// サーバー起動を非同期で実行
@@ -25,29 +27,16 @@ func TestRun(t *testing.T) {
 			cancel()                    // シャットダウンシグナル送信
 		}()
 
-		err := server.RunWithMockConfig(ctx, mockConfigSuccess)
+		err := server.Run(ctx)
 		assert.NoError(t, err)
 	})
 
 	t.Run("failure_initializeConfig", func(t *testing.T) {
-		ctx := context.Background()
+		// 設定が不正な場合は DB に接続せずに失敗する
+		t.Setenv("DSN", "oracle://localhost/finatext_db")
 
-		// Mock Config Initialization Error
-		err := server.RunWithMockConfig(ctx, mockConfigFailure)
+		err := server.Run(context.Background())
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "mock config error")
+		assert.Contains(t, err.Error(), "failed to initialize config")
 	})
 }
-
-// mockConfigSuccess は成功する MockConfigLoader
-func mockConfigSuccess() (*config.Config, error) {
-	return &config.Config{
-		Port: ":8080",
-		DSN:  "root:password@tcp(localhost:3306)/",
-	}, nil
-}
-
-// mockConfigFailure は失敗する MockConfigLoader
-func mockConfigFailure() (*config.Config, error) {
-	return nil, errors.New("mock config error")
-}
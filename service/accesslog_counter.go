@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+)
+
+// カウンターを生のアクセスログと比較し、件数の食い違う郵便番号を返す
+func (s *AccessLogService) CheckCounters() ([]entity.AccessLogCounterMismatch, error) {
+	stored, err := s.LogRepo.ListCounters()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch access log counters: %w", err)
+	}
+	actual, err := s.LogRepo.ComputeCounters()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute access log counters: %w", err)
+	}
+	return CompareCounters(stored, actual), nil
+}
+
+// カウンターを生のアクセスログから作り直す
+func (s *AccessLogService) RebuildCounters() error {
+	if err := s.LogRepo.RebuildCounters(); err != nil {
+		return fmt.Errorf("failed to rebuild access log counters: %w", err)
+	}
+	return nil
+}
+
+// カウンターとアクセスログから計算した件数を比較し、食い違う郵便番号を郵便番号順に返す
+func CompareCounters(stored, actual []entity.AccessLog) []entity.AccessLogCounterMismatch {
+	counts := make(map[string]*entity.AccessLogCounterMismatch)
+	get := func(postalCode string) *entity.AccessLogCounterMismatch {
+		m, ok := counts[postalCode]
+		if !ok {
+			m = &entity.AccessLogCounterMismatch{PostalCode: postalCode}
+			counts[postalCode] = m
+		}
+		return m
+	}
+	for _, c := range stored {
+		get(c.PostalCode).Counted += c.RequestCount
+	}
+	for _, c := range actual {
+		get(c.PostalCode).Actual += c.RequestCount
+	}
+
+	var mismatches []entity.AccessLogCounterMismatch
+	for _, m := range counts {
+		if m.Counted != m.Actual {
+			mismatches = append(mismatches, *m)
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].PostalCode < mismatches[j].PostalCode
+	})
+	return mismatches
+}
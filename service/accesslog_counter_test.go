@@ -0,0 +1,34 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareCounters(t *testing.T) {
+	stored := []entity.AccessLog{
+		{PostalCode: "1000001", RequestCount: 3},
+		{PostalCode: "1020072", RequestCount: 5},
+		{PostalCode: "2790031", RequestCount: 1},
+	}
+	actual := []entity.AccessLog{
+		{PostalCode: "0600000", RequestCount: 2},
+		{PostalCode: "1000001", RequestCount: 3},
+		{PostalCode: "1020072", RequestCount: 4},
+	}
+
+	assert.Equal(t, []entity.AccessLogCounterMismatch{
+		{PostalCode: "0600000", Counted: 0, Actual: 2},
+		{PostalCode: "1020072", Counted: 5, Actual: 4},
+		{PostalCode: "2790031", Counted: 1, Actual: 0},
+	}, service.CompareCounters(stored, actual))
+}
+
+func TestCompareCountersConsistent(t *testing.T) {
+	logs := []entity.AccessLog{{PostalCode: "1000001", RequestCount: 3}}
+
+	assert.Empty(t, service.CompareCounters(logs, logs))
+}
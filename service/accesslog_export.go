@@ -0,0 +1,161 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+)
+
+// アクセスログのエクスポート形式
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+)
+
+// 対応していないエクスポート形式を指定した場合のエラー
+var ErrUnsupportedExportFormat = errors.New("unsupported export format")
+
+// 書き出し先に送る間隔（件数）
+const exportFlushRows = 1000
+
+// AccessLogExporter は期間内の生のアクセスログを保存順に読み出す
+type AccessLogExporter interface {
+	StreamAccessLogs(ctx context.Context, from, to time.Time, fn func(entity.AccessLogEntry) error) error
+}
+
+// CSV のヘッダー
+var exportCSVHeader = []string{"id", "postal_code", "status", "latency_ms", "client_ip", "client_id", "user_agent", "request_id", "hit_count", "error_class", "prefecture_code", "city_code", "created_at"}
+
+// exportRecord は NDJSON の 1 行（CSV と同じ項目・単位で書き出す）
+type exportRecord struct {
+	ID             string    `json:"id"`
+	PostalCode     string    `json:"postal_code"`
+	Status         int       `json:"status"`
+	LatencyMs      float64   `json:"latency_ms"`
+	ClientIP       string    `json:"client_ip"`
+	ClientID       string    `json:"client_id"`
+	UserAgent      string    `json:"user_agent"`
+	RequestID      string    `json:"request_id"`
+	HitCount       int       `json:"hit_count"`
+	ErrorClass     string    `json:"error_class"`
+	PrefectureCode string    `json:"prefecture_code"`
+	CityCode       string    `json:"city_code"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// 対応しているエクスポート形式かどうか
+func IsExportFormat(format string) bool {
+	return format == ExportCSV || format == ExportNDJSON
+}
+
+// 期間内の生のアクセスログを指定した形式で w に書き出し、書き出した件数を返す
+// 一定件数ごとに w に送り、w が Flush() を持つ場合はあわせて呼び出す
+func (s *AccessLogService) ExportAccessLogs(ctx context.Context, w io.Writer, format string, from, to time.Time) (int, error) {
+	return ExportAccessLogs(ctx, s.LogRepo, w, format, from, to)
+}
+
+// 期間内の生のアクセスログを exporter から読み出し、指定した形式で w に書き出す
+func ExportAccessLogs(ctx context.Context, exporter AccessLogExporter, w io.Writer, format string, from, to time.Time) (int, error) {
+	if !IsExportFormat(format) {
+		return 0, ErrUnsupportedExportFormat
+	}
+
+	bw := bufio.NewWriter(w)
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+		return nil
+	}
+
+	var encode func(entity.AccessLogEntry) error
+	if format == ExportCSV {
+		cw := csv.NewWriter(bw)
+		if err := cw.Write(exportCSVHeader); err != nil {
+			return 0, err
+		}
+		encode = func(entry entity.AccessLogEntry) error {
+			if err := cw.Write(exportCSVRecord(entry)); err != nil {
+				return err
+			}
+			// csv.Writer のバッファを bufio.Writer に移す
+			cw.Flush()
+			return cw.Error()
+		}
+	} else {
+		enc := json.NewEncoder(bw)
+		encode = func(entry entity.AccessLogEntry) error {
+			return enc.Encode(newExportRecord(entry))
+		}
+	}
+
+	n := 0
+	err := exporter.StreamAccessLogs(ctx, from, to, func(entry entity.AccessLogEntry) error {
+		if err := encode(entry); err != nil {
+			return fmt.Errorf("failed to write access log: %w", err)
+		}
+		n++
+		if n%exportFlushRows == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, flush()
+}
+
+// アクセスログを CSV の 1 行に変換
+func exportCSVRecord(entry entity.AccessLogEntry) []string {
+	return []string{
+		entry.ID,
+		entry.PostalCode,
+		strconv.Itoa(entry.Status),
+		strconv.FormatFloat(exportLatencyMs(entry.Latency), 'f', -1, 64),
+		entry.ClientIP,
+		entry.ClientID,
+		entry.UserAgent,
+		entry.RequestID,
+		strconv.Itoa(entry.HitCount),
+		entry.ErrorClass,
+		entry.PrefectureCode,
+		entry.CityCode,
+		entry.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// アクセスログを NDJSON の 1 行に変換
+func newExportRecord(entry entity.AccessLogEntry) exportRecord {
+	return exportRecord{
+		ID:             entry.ID,
+		PostalCode:     entry.PostalCode,
+		Status:         entry.Status,
+		LatencyMs:      exportLatencyMs(entry.Latency),
+		ClientIP:       entry.ClientIP,
+		ClientID:       entry.ClientID,
+		UserAgent:      entry.UserAgent,
+		RequestID:      entry.RequestID,
+		HitCount:       entry.HitCount,
+		ErrorClass:     entry.ErrorClass,
+		PrefectureCode: entry.PrefectureCode,
+		CityCode:       entry.CityCode,
+		CreatedAt:      entry.CreatedAt.UTC(),
+	}
+}
+
+// 処理時間をミリ秒にする（マイクロ秒の精度）
+func exportLatencyMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
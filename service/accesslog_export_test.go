@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceExporter は保持しているアクセスログを期間で絞り込んで返す service.AccessLogExporter のモック
+type sliceExporter struct {
+	entries []entity.AccessLogEntry
+}
+
+func (m *sliceExporter) StreamAccessLogs(ctx context.Context, from, to time.Time, fn func(entity.AccessLogEntry) error) error {
+	for _, entry := range m.entries {
+		if (!from.IsZero() && entry.CreatedAt.Before(from)) || (!to.IsZero() && !entry.CreatedAt.Before(to)) {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportEntries() []entity.AccessLogEntry {
+	return []entity.AccessLogEntry{
+		{ID: "a1", PostalCode: "1000001", Status: 200, Latency: 1500 * time.Microsecond, ClientIP: "192.0.2.1", ClientID: "c1",
+			UserAgent: "curl/8.0, test", RequestID: "r1", HitCount: 1, PrefectureCode: "13", CityCode: "13101",
+			CreatedAt: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)},
+		{ID: "a2", PostalCode: "0000000", Status: 404, ErrorClass: entity.ErrorClassNotFound,
+			CreatedAt: time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)},
+	}
+}
+
+func TestExportAccessLogsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := service.ExportAccessLogs(context.Background(), &sliceExporter{entries: exportEntries()}, &buf, service.ExportCSV, time.Time{}, time.Time{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, n)
+	assert.Equal(t, strings.Join([]string{
+		"id,postal_code,status,latency_ms,client_ip,client_id,user_agent,request_id,hit_count,error_class,prefecture_code,city_code,created_at",
+		`a1,1000001,200,1.5,192.0.2.1,c1,"curl/8.0, test",r1,1,,13,13101,2024-01-01T09:00:00Z`,
+		"a2,0000000,404,0,,,,,0,not_found,,,2024-01-02T09:00:00Z",
+	}, "\n")+"\n", buf.String())
+}
+
+func TestExportAccessLogsNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	from := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	n, err := service.ExportAccessLogs(context.Background(), &sliceExporter{entries: exportEntries()}, &buf, service.ExportNDJSON, from, time.Time{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, n)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 1)
+	assert.JSONEq(t, `{"id":"a2","postal_code":"0000000","status":404,"latency_ms":0,"client_ip":"","client_id":"","user_agent":"",
+		"request_id":"","hit_count":0,"error_class":"not_found","prefecture_code":"","city_code":"","created_at":"2024-01-02T09:00:00Z"}`, lines[0])
+}
+
+func TestExportAccessLogsLatencyUnit(t *testing.T) {
+	entries := exportEntries()[:1]
+
+	// CSV と NDJSON で同じミリ秒の値を書き出す
+	var csvBuf, ndjsonBuf bytes.Buffer
+	_, err := service.ExportAccessLogs(context.Background(), &sliceExporter{entries: entries}, &csvBuf, service.ExportCSV, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	_, err = service.ExportAccessLogs(context.Background(), &sliceExporter{entries: entries}, &ndjsonBuf, service.ExportNDJSON, time.Time{}, time.Time{})
+	require.NoError(t, err)
+
+	assert.Contains(t, csvBuf.String(), ",1.5,")
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(ndjsonBuf.Bytes(), &record))
+	assert.Equal(t, 1.5, record["latency_ms"])
+	assert.NotContains(t, record, "latency_ns")
+}
+
+func TestExportAccessLogsUnsupportedFormat(t *testing.T) {
+	_, err := service.ExportAccessLogs(context.Background(), &sliceExporter{}, &bytes.Buffer{}, "xml", time.Time{}, time.Time{})
+
+	assert.ErrorIs(t, err, service.ErrUnsupportedExportFormat)
+}
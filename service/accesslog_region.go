@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+)
+
+// 期間内のアクセス数を都道府県または市区町村ごとに集計し、全体に占める割合とともに返す
+func (s *AccessLogService) GetRegionCounts(level string, from, to time.Time) (*entity.AccessLogRegionList, error) {
+	// 日次の集約には地域がないため、生のアクセスログの保持期間より前は集計できない
+	if since := s.RawLogsSince(time.Now()); !since.IsZero() && (from.IsZero() || from.Before(since)) {
+		return nil, ErrBeforeRetention
+	}
+	counts, err := s.LogRepo.CountAccessLogsByRegion(level, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch access log region counts: %w", err)
+	}
+	return SummarizeRegions(level, counts, s.Regions), nil
+}
+
+// 地域コードごとのアクセス数に名前と割合を付け、アクセス数の多い順に並べる
+// regions が nil の場合は名前を空にする
+func SummarizeRegions(level string, counts []entity.AccessLogRegionCount, regions *RegionService) *entity.AccessLogRegionList {
+	list := &entity.AccessLogRegionList{Level: level, Regions: []entity.AccessLogRegion{}}
+	for _, count := range counts {
+		list.Total += count.RequestCount
+		if count.Code == "" {
+			list.Unresolved += count.RequestCount
+			continue
+		}
+
+		region := entity.AccessLogRegion{Code: count.Code, RequestCount: count.RequestCount}
+		if level == entity.RegionLevelCity {
+			region.PrefectureCode = count.Code[:2]
+		}
+		if regions != nil {
+			if level == entity.RegionLevelCity {
+				if city, _, ok := regions.Towns(count.Code); ok {
+					region.Name = city.Name
+				}
+			}
+			if prefecture, _, ok := regions.Cities(count.Code[:2]); ok {
+				if level == entity.RegionLevelCity {
+					region.Prefecture = prefecture.Name
+				} else {
+					region.Name = prefecture.Name
+				}
+			}
+		}
+		list.Regions = append(list.Regions, region)
+	}
+
+	for i := range list.Regions {
+		list.Regions[i].Share = percentage(list.Regions[i].RequestCount, list.Total)
+	}
+	sort.Slice(list.Regions, func(i, j int) bool {
+		if list.Regions[i].RequestCount != list.Regions[j].RequestCount {
+			return list.Regions[i].RequestCount > list.Regions[j].RequestCount
+		}
+		return list.Regions[i].Code < list.Regions[j].Code
+	})
+	return list
+}
+
+// 全体に占める割合 [%]（小数点以下 2 桁に丸める）
+func percentage(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(total)*10000) / 100
+}
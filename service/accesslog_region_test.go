@@ -0,0 +1,78 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func regionRecords() []entity.PostalRecord {
+	return []entity.PostalRecord{
+		{PostalCode: "5016121", JISCode: "21201", Prefecture: "岐阜県", City: "岐阜市", Town: "柳津町"},
+		{PostalCode: "5011100", JISCode: "21213", Prefecture: "岐阜県", City: "各務原市"},
+		{PostalCode: "5011101", JISCode: "21201", Prefecture: "岐阜県", City: "岐阜市"},
+		{PostalCode: "5011101", JISCode: "21213", Prefecture: "岐阜県", City: "各務原市"},
+		{PostalCode: "1000005", JISCode: "13101", Prefecture: "東京都", City: "千代田区", Town: "丸の内"},
+	}
+}
+
+func TestRegionOf(t *testing.T) {
+	s := service.NewRegionService(regionRecords())
+
+	prefectureCode, cityCode := s.RegionOf("5016121")
+	assert.Equal(t, "21", prefectureCode)
+	assert.Equal(t, "21201", cityCode)
+
+	// 複数の市区町村にまたがる郵便番号は都道府県のみ
+	prefectureCode, cityCode = s.RegionOf("5011101")
+	assert.Equal(t, "21", prefectureCode)
+	assert.Empty(t, cityCode)
+
+	prefectureCode, cityCode = s.RegionOf("9999999")
+	assert.Empty(t, prefectureCode)
+	assert.Empty(t, cityCode)
+}
+
+func TestSummarizeRegionsPrefecture(t *testing.T) {
+	counts := []entity.AccessLogRegionCount{
+		{Code: "13", RequestCount: 1},
+		{Code: "21", RequestCount: 6},
+		{Code: "", RequestCount: 1},
+	}
+
+	list := service.SummarizeRegions(entity.RegionLevelPrefecture, counts, service.NewRegionService(regionRecords()))
+
+	assert.Equal(t, &entity.AccessLogRegionList{
+		Level:      entity.RegionLevelPrefecture,
+		Total:      8,
+		Unresolved: 1,
+		Regions: []entity.AccessLogRegion{
+			{Code: "21", Name: "岐阜県", RequestCount: 6, Share: 75},
+			{Code: "13", Name: "東京都", RequestCount: 1, Share: 12.5},
+		},
+	}, list)
+}
+
+func TestSummarizeRegionsCity(t *testing.T) {
+	counts := []entity.AccessLogRegionCount{
+		{Code: "21213", RequestCount: 1},
+		{Code: "21201", RequestCount: 1},
+		{Code: "13101", RequestCount: 1},
+	}
+
+	list := service.SummarizeRegions(entity.RegionLevelCity, counts, service.NewRegionService(regionRecords()))
+
+	assert.Equal(t, []entity.AccessLogRegion{
+		{Code: "13101", Name: "千代田区", PrefectureCode: "13", Prefecture: "東京都", RequestCount: 1, Share: 33.33},
+		{Code: "21201", Name: "岐阜市", PrefectureCode: "21", Prefecture: "岐阜県", RequestCount: 1, Share: 33.33},
+		{Code: "21213", Name: "各務原市", PrefectureCode: "21", Prefecture: "岐阜県", RequestCount: 1, Share: 33.33},
+	}, list.Regions)
+}
+
+func TestSummarizeRegionsWithoutDataset(t *testing.T) {
+	list := service.SummarizeRegions(entity.RegionLevelPrefecture, []entity.AccessLogRegionCount{{Code: "13", RequestCount: 2}}, nil)
+
+	assert.Equal(t, []entity.AccessLogRegion{{Code: "13", RequestCount: 2, Share: 100}}, list.Regions)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// バッチの間に空ける時間（他のクエリがロックを待ち続けないようにする）
+const purgeBatchPause = 100 * time.Millisecond
+
+// AccessLogPurger は保持期間を過ぎたアクセスログを日次に集約してから削除する
+type AccessLogPurger interface {
+	RollupAndPurge(before time.Time, batchSize int) (int, error)
+}
+
+// AccessLogRetention は保持期間を過ぎた生のアクセスログを少しずつ集約・削除する
+type AccessLogRetention struct {
+	Repo      AccessLogPurger
+	Days      int // 生のアクセスログを残す日数
+	BatchSize int
+}
+
+// 新しい AccessLogRetention を作成
+func NewAccessLogRetention(repo AccessLogPurger, days, batchSize int) *AccessLogRetention {
+	return &AccessLogRetention{Repo: repo, Days: days, BatchSize: batchSize}
+}
+
+// 保持期間の境界（UTC で days 日前の 0 時）を返す
+func RetentionCutoff(now time.Time, days int) time.Time {
+	day := now.UTC().AddDate(0, 0, -days)
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// 保持期間を過ぎたアクセスログがなくなるまでバッチごとに集約・削除し、削除した件数を返す
+func (r *AccessLogRetention) Purge(ctx context.Context, now time.Time) (int, error) {
+	cutoff := RetentionCutoff(now, r.Days)
+	total := 0
+	for {
+		n, err := r.Repo.RollupAndPurge(cutoff, r.BatchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < r.BatchSize {
+			return total, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-time.After(purgeBatchPause):
+		}
+	}
+}
+
+// 起動時と、ctx が終了するまで一定間隔で保持期間を過ぎたアクセスログを集約・削除する
+func (r *AccessLogRetention) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.Purge(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to purge expired access logs: %v", err)
+		}
+		if n > 0 {
+			log.Printf("Rolled up and purged %d access logs older than %d days", n, r.Days)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/dkpcb/finatext_kadai_2/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchPurger は残り件数を持つ service.AccessLogPurger のモック
+type batchPurger struct {
+	remaining int
+	befores   []time.Time
+}
+
+func (m *batchPurger) RollupAndPurge(before time.Time, batchSize int) (int, error) {
+	m.befores = append(m.befores, before)
+	n := batchSize
+	if m.remaining < n {
+		n = m.remaining
+	}
+	m.remaining -= n
+	return n, nil
+}
+
+func TestRetentionCutoff(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2024, 3, 10, 5, 0, 0, 0, jst) // UTC では 2024-03-09 20:00
+
+	assert.Equal(t, time.Date(2024, 2, 8, 0, 0, 0, 0, time.UTC), service.RetentionCutoff(now, 30))
+}
+
+func TestAccessLogRetentionPurgesInBatches(t *testing.T) {
+	purger := &batchPurger{remaining: 25}
+	r := service.NewAccessLogRetention(purger, 30, 10)
+
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	n, err := r.Purge(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 25, n)
+
+	// 10 件・10 件・5 件の 3 回に分け、すべて同じ境界で削除する
+	assert.Len(t, purger.befores, 3)
+	for _, before := range purger.befores {
+		assert.Equal(t, time.Date(2024, 2, 9, 0, 0, 0, 0, time.UTC), before)
+	}
+}
+
+func TestAccessLogServiceRejectsRangesBeforeRetention(t *testing.T) {
+	s := service.NewAccessLogService(infra.NewMemoryAccessLogRepository())
+	now := time.Now()
+	assert.True(t, s.RawLogsSince(now).IsZero())
+
+	s.Retention = service.NewAccessLogRetention(nil, 30, 10)
+	since := s.RawLogsSince(now)
+	assert.Equal(t, service.RetentionCutoff(now, 30), since)
+
+	// 日次に集約した期間は時間・地域ごとに集計できない
+	_, err := s.GetTimeseries("", util.IntervalDay, since.Add(-time.Hour), now, time.UTC)
+	assert.ErrorIs(t, err, service.ErrBeforeRetention)
+	_, err = s.GetRegionCounts(entity.RegionLevelPrefecture, time.Time{}, now)
+	assert.ErrorIs(t, err, service.ErrBeforeRetention)
+
+	_, err = s.GetTimeseries("", util.IntervalDay, since, now, time.UTC)
+	assert.NoError(t, err)
+	_, err = s.GetRegionCounts(entity.RegionLevelPrefecture, since, now)
+	assert.NoError(t, err)
+}
+
+func TestAccessLogServiceCoversWholeRolledUpDays(t *testing.T) {
+	repo := infra.NewMemoryAccessLogRepository()
+	s := service.NewAccessLogService(repo)
+	now := time.Now()
+	since := service.RetentionCutoff(now, 3)
+	day := since.AddDate(0, 0, -2) // 日次に集約した日
+
+	require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{
+		{ID: "1", PostalCode: "1000001", CreatedAt: day.Add(2 * time.Hour)},
+		{ID: "2", PostalCode: "1000001", CreatedAt: day.Add(20 * time.Hour)},
+	}))
+	s.Retention = service.NewAccessLogRetention(repo, 3, 10)
+	_, err := s.Retention.Purge(context.Background(), now)
+	require.NoError(t, err)
+
+	// 集約した日の途中を指定すると日の境界に広げ、その日の件数をすべて数える
+	from, to := s.CoveredRange(day.Add(12*time.Hour), day.Add(13*time.Hour), now)
+	assert.Equal(t, day, from)
+	assert.Equal(t, day.AddDate(0, 0, 1), to)
+	logs, _, err := s.GetAccessLogs(entity.AccessLogQuery{From: day.Add(12 * time.Hour), To: day.Add(13 * time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, 2, logs[0].RequestCount)
+
+	// 生のアクセスログが残っている期間と日の境界はそのまま
+	from, to = s.CoveredRange(since.Add(time.Hour), now, now)
+	assert.Equal(t, since.Add(time.Hour), from)
+	assert.Equal(t, now, to)
+	from, to = s.CoveredRange(day, day.AddDate(0, 0, 1), now)
+	assert.Equal(t, day, from)
+	assert.Equal(t, day.AddDate(0, 0, 1), to)
+}
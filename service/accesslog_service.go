@@ -1,37 +1,236 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"time"
+	"unicode/utf8"
 
 	"github.com/dkpcb/finatext_kadai_2/entity"
-	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/dkpcb/finatext_kadai_2/util"
 )
 
+// 時系列の集計単位の数の上限
+const MaxTimeseriesBuckets = 2000
+
+// 時系列の集計単位の数が上限を超えた場合のエラー
+var ErrTooManyBuckets = errors.New("too many timeseries buckets")
+
+// 日次に集約して生のアクセスログを削除した期間を、時間・地域ごとに集計しようとした場合のエラー
+var ErrBeforeRetention = errors.New("range starts before the raw access log retention period")
+
 type AccessLogService struct {
-	LogRepo *infra.AccessLogRepository // ポインタ型
+	LogRepo   entity.AccessLogRepository // MySQL・SQLite・メモリのいずれか
+	Writer    *AccessLogWriter           // 非同期の書き込み先（nil の場合は同期的に保存する）
+	Spool     *SpoolingAccessLogSink     // DB に接続できない間の退避先（任意）
+	Retention *AccessLogRetention        // 保持期間を過ぎたアクセスログの集約・削除（任意）
+	Regions   *RegionService             // 郵便番号から地域を解決するローカルデータセット（任意）
+	Trending  *TrendingCounter           // 直近にアクセスの多い郵便番号の集計（任意）
+	// クライアントの識別子のソルト（API キーや IP アドレスをそのまま保存しないため）
+	ClientIDSalt []byte
 }
 
 // 新しい AccessLogService を作成
-func NewAccessLogService(logRepo *infra.AccessLogRepository) *AccessLogService {
+func NewAccessLogService(logRepo entity.AccessLogRepository) *AccessLogService {
 	return &AccessLogService{LogRepo: logRepo}
 }
 
-// 郵便番号を含むアクセスログを保存
-func (s *AccessLogService) SaveAccessLog(postalCode string) error {
-	// 現在時刻を取得してログを保存
-	now := time.Now()
-	return s.LogRepo.InsertAccessLog(postalCode, now)
+// 住所検索の結果を含むアクセスログを保存
+func (s *AccessLogService) SaveAccessLog(entry entity.AccessLogEntry) error {
+	// 日時が未指定の場合は現在時刻で保存
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if entry.ID == "" {
+		entry.ID = newAccessLogID()
+	}
+	// 長すぎる郵便番号でバッチ全体の保存が失敗しないよう、カラムの長さ（文字数）に切り詰める
+	// 全角数字などのマルチバイト文字を途中で切らないよう、バイトではなく文字で数える
+	if utf8.RuneCountInString(entry.PostalCode) > entity.AccessLogPostalCodeMaxLength {
+		entry.PostalCode = string([]rune(entry.PostalCode)[:entity.AccessLogPostalCodeMaxLength])
+	}
+	if s.Regions != nil && entry.PrefectureCode == "" {
+		entry.PrefectureCode, entry.CityCode = s.Regions.RegionOf(entry.PostalCode)
+	}
+	if s.Trending != nil {
+		s.Trending.Record(entry.PostalCode, entry.CreatedAt)
+	}
+	if s.Writer != nil {
+		return s.Writer.Write(entry)
+	}
+	if s.Spool != nil {
+		return s.Spool.InsertAccessLogs([]entity.AccessLogEntry{entry})
+	}
+	return s.LogRepo.InsertAccessLog(entry)
+}
+
+// API キーまたは IP アドレスから、元の値に戻せないクライアントの識別子を返す
+func (s *AccessLogService) ClientID(key string) string {
+	if key == "" {
+		return ""
+	}
+	return util.ClientID(s.ClientIDSalt, key)
+}
+
+// アクセスログの一意な ID（128 ビットの乱数の 16 進表記）
+func newAccessLogID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// 非同期の書き込みを止め、溜まっているアクセスログをすべて保存する
+func (s *AccessLogService) Close(ctx context.Context) error {
+	if s.Writer == nil {
+		return nil
+	}
+	err := s.Writer.Close(ctx)
+	stats := s.Writer.Stats()
+	log.Printf("Access log writer stopped: enqueued=%d flushed=%d dropped=%d failed=%d pending=%d",
+		stats.Enqueued, stats.Flushed, stats.Dropped, stats.Failed, stats.Pending)
+	return err
+}
+
+// AccessLogPipelineStats はアクセスログの書き込みの累計件数（使っていないものは省略する）
+type AccessLogPipelineStats struct {
+	Writer *AccessLogWriterStats `json:"writer,omitempty"`
+	Spool  *AccessLogSpoolStats  `json:"spool,omitempty"`
 }
 
-// リクエスト回数を集計し、降順で返す
-func (s *AccessLogService) GetAccessLogs() ([]entity.AccessLog, error) {
+// AccessLogSpoolStats はスプールに退避した件数と再送した件数の累計
+type AccessLogSpoolStats struct {
+	Spooled  int64 `json:"spooled"`
+	Replayed int64 `json:"replayed"`
+}
+
+// 非同期の書き込みとスプールの現在の累計件数を返す
+func (s *AccessLogService) PipelineStats() AccessLogPipelineStats {
+	var stats AccessLogPipelineStats
+	if s.Writer != nil {
+		writer := s.Writer.Stats()
+		stats.Writer = &writer
+	}
+	if s.Spool != nil {
+		spooled, replayed := s.Spool.Counts()
+		stats.Spool = &AccessLogSpoolStats{Spooled: spooled, Replayed: replayed}
+	}
+	return stats
+}
+
+// 条件に一致するアクセスログを集計し、次のページがある場合はその位置を返す
+// 期間は CoveredRange で日次に集約した日の境界に広げてから集計する
+func (s *AccessLogService) GetAccessLogs(q entity.AccessLogQuery) ([]entity.AccessLog, *entity.AccessLogCursor, error) {
+	q.From, q.To = s.CoveredRange(q.From, q.To, time.Now())
+
+	// 次のページの有無を判定するため 1 件多く取得
+	limit := q.Limit
+	if limit > 0 {
+		q.Limit = limit + 1
+	}
+
 	// アクセスログを集計
-	logs, err := s.LogRepo.GetAccessLogs()
+	logs, err := s.LogRepo.GetAccessLogs(q)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch access logs: %w", err)
+	}
+
+	var next *entity.AccessLogCursor
+	if limit > 0 && len(logs) > limit {
+		logs = logs[:limit]
+		last := logs[limit-1]
+		next = &entity.AccessLogCursor{Sort: q.Sort, Order: q.Order, RequestCount: last.RequestCount, LastSeen: last.LastSeen, PostalCode: last.PostalCode}
+	}
+
+	// ページ内の郵便番号についてクライアントの種類数を推定
+	postalCodes := make([]string, len(logs))
+	for i, log := range logs {
+		postalCodes[i] = log.PostalCode
+	}
+	uniqueClients, err := s.LogRepo.CountUniqueClients(postalCodes, q.From, q.To)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch access logs: %w", err)
+		return nil, nil, fmt.Errorf("failed to count unique clients: %w", err)
+	}
+	for i := range logs {
+		logs[i].UniqueClients = uniqueClients[logs[i].PostalCode]
+	}
+	return logs, next, nil
+}
+
+// 生のアクセスログが残っている最も古い日時（保持期間を設定していない場合はゼロ値）
+// これより前は日次の集約しか残っていないため、時間・地域ごとには集計できない
+func (s *AccessLogService) RawLogsSince(now time.Time) time.Time {
+	if s.Retention == nil {
+		return time.Time{}
+	}
+	return RetentionCutoff(now, s.Retention.Days)
+}
+
+// 日次に集約した日（保持期間より前）の途中から・途中までの期間を、その日の境界（UTC）に広げて返す
+// 集約した日は日の途中で分けられないため、広げないとその日の件数が含まれず少なく数えてしまう
+// 保持期間を設定していない場合やゼロ値の日時はそのまま返す
+func (s *AccessLogService) CoveredRange(from, to, now time.Time) (time.Time, time.Time) {
+	since := s.RawLogsSince(now)
+	if since.IsZero() {
+		return from, to
+	}
+	if !from.IsZero() && from.Before(since) {
+		from = from.UTC().Truncate(24 * time.Hour)
+	}
+	if !to.IsZero() && to.Before(since) {
+		if day := to.UTC().Truncate(24 * time.Hour); day.Before(to) {
+			to = day.AddDate(0, 0, 1)
+		}
+	}
+	return from, to
+}
+
+// 期間内のアクセス数を指定したタイムゾーンの集計単位ごとに返す（アクセスのない単位は 0 件）
+// 期間は集計単位の境界に広げる。DB では UTC の 1 時間ごとに集計するため、loc の時差は 1 時間単位を前提とする
+// 広げた期間が生のアクセスログの保持期間より前から始まる場合は ErrBeforeRetention を返す
+func (s *AccessLogService) GetTimeseries(postalCode, interval string, from, to time.Time, loc *time.Location) (*entity.AccessLogTimeseries, error) {
+	start := util.TruncateToInterval(from, interval, loc)
+	end := util.TruncateToInterval(to, interval, loc)
+	if end.Before(to) {
+		end = util.AddInterval(end, interval)
+	}
+	if start.Before(s.RawLogsSince(time.Now())) {
+		return nil, ErrBeforeRetention
+	}
+
+	// 集計単位を 0 件で埋める
+	var buckets []entity.AccessLogBucket
+	index := make(map[int64]int)
+	for t := start; t.Before(end); t = util.AddInterval(t, interval) {
+		if len(buckets) >= MaxTimeseriesBuckets {
+			return nil, ErrTooManyBuckets
+		}
+		index[t.Unix()] = len(buckets)
+		buckets = append(buckets, entity.AccessLogBucket{Start: t})
+	}
+
+	// 1 時間ごとのアクセス数を集計単位に振り分ける
+	hourly, err := s.LogRepo.CountAccessLogsByHour(postalCode, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch access log timeseries: %w", err)
+	}
+	for _, h := range hourly {
+		if i, ok := index[util.TruncateToInterval(h.Start, interval, loc).Unix()]; ok {
+			buckets[i].Count += h.Count
+		}
 	}
 
-	// ログを返す
-	return logs, nil
+	return &entity.AccessLogTimeseries{
+		PostalCode: postalCode,
+		Interval:   interval,
+		TimeZone:   loc.String(),
+		From:       start,
+		To:         end,
+		Buckets:    buckets,
+	}, nil
 }
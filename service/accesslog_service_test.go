@@ -0,0 +1,72 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/dkpcb/finatext_kadai_2/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTimeseries(t *testing.T) {
+	repo := infra.NewMemoryAccessLogRepository()
+	require.NoError(t, repo.InsertAccessLogs([]entity.AccessLogEntry{
+		{PostalCode: "1000001", CreatedAt: time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)}, // JST 3/1 23:30
+		{PostalCode: "1000001", CreatedAt: time.Date(2024, 3, 1, 15, 10, 0, 0, time.UTC)}, // JST 3/2 00:10
+		{PostalCode: "1000001", CreatedAt: time.Date(2024, 3, 1, 16, 0, 0, 0, time.UTC)},  // JST 3/2 01:00
+		{PostalCode: "5016121", CreatedAt: time.Date(2024, 3, 1, 16, 0, 0, 0, time.UTC)},
+	}))
+	s := service.NewAccessLogService(repo)
+	jst := time.FixedZone("JST", 9*60*60)
+
+	// 期間は JST の日の境界に広げ、アクセスのない日は 0 件で埋める
+	timeseries, err := s.GetTimeseries("1000001", util.IntervalDay, time.Date(2024, 3, 1, 12, 0, 0, 0, jst), time.Date(2024, 3, 3, 12, 0, 0, 0, jst), jst)
+	require.NoError(t, err)
+	assert.True(t, time.Date(2024, 3, 1, 0, 0, 0, 0, jst).Equal(timeseries.From))
+	assert.True(t, time.Date(2024, 3, 4, 0, 0, 0, 0, jst).Equal(timeseries.To))
+	var counts []int
+	for i, bucket := range timeseries.Buckets {
+		assert.True(t, time.Date(2024, 3, 1+i, 0, 0, 0, 0, jst).Equal(bucket.Start))
+		counts = append(counts, bucket.Count)
+	}
+	assert.Equal(t, []int{1, 2, 0}, counts)
+
+	// 郵便番号を指定しない場合はすべてのアクセスを数える
+	timeseries, err = s.GetTimeseries("", util.IntervalDay, time.Date(2024, 3, 2, 0, 0, 0, 0, jst), time.Date(2024, 3, 3, 0, 0, 0, 0, jst), jst)
+	require.NoError(t, err)
+	require.Len(t, timeseries.Buckets, 1)
+	assert.Equal(t, 3, timeseries.Buckets[0].Count)
+}
+
+func TestGetTimeseriesTooManyBuckets(t *testing.T) {
+	s := service.NewAccessLogService(infra.NewMemoryAccessLogRepository())
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := s.GetTimeseries("", util.IntervalHour, from, from.Add(service.MaxTimeseriesBuckets*time.Hour), time.UTC)
+	assert.NoError(t, err)
+	_, err = s.GetTimeseries("", util.IntervalHour, from, from.Add((service.MaxTimeseriesBuckets+1)*time.Hour), time.UTC)
+	assert.ErrorIs(t, err, service.ErrTooManyBuckets)
+}
+
+func TestSaveAccessLogTruncatesPostalCodeByRunes(t *testing.T) {
+	repo := infra.NewMemoryAccessLogRepository()
+	s := service.NewAccessLogService(repo)
+
+	// 全角数字は 1 文字 3 バイトのため、バイトで切ると UTF-8 として壊れる
+	require.NoError(t, s.SaveAccessLog(entity.AccessLogEntry{PostalCode: "１２３４５６７８９"}))
+	require.NoError(t, s.SaveAccessLog(entity.AccessLogEntry{PostalCode: "1234567890"}))
+
+	logs, err := repo.GetAccessLogs(entity.AccessLogQuery{})
+	require.NoError(t, err)
+	var postalCodes []string
+	for _, log := range logs {
+		assert.True(t, utf8.ValidString(log.PostalCode), log.PostalCode)
+		postalCodes = append(postalCodes, log.PostalCode)
+	}
+	assert.ElementsMatch(t, []string{"１２３４５６７８", "12345678"}, postalCodes)
+}
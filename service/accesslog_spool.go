@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+)
+
+// AccessLogStore は接続確認のできるアクセスログの保存先（DB）
+type AccessLogStore interface {
+	AccessLogSink
+	Ping() error
+}
+
+// AccessLogSpool は DB に保存できなかったアクセスログの退避先
+type AccessLogSpool interface {
+	Append(entries []entity.AccessLogEntry) error
+	// 退避したアクセスログを insert で再送し、再送した件数を返す
+	Replay(insert func([]entity.AccessLogEntry) error) (int, error)
+}
+
+// SpoolingAccessLogSink は DB に接続できない間アクセスログをスプールに退避し、復旧後に再送する
+type SpoolingAccessLogSink struct {
+	Store AccessLogStore
+	Spool AccessLogSpool
+
+	spooled, replayed atomic.Int64
+}
+
+// 新しい SpoolingAccessLogSink を作成
+func NewSpoolingAccessLogSink(store AccessLogStore, spool AccessLogSpool) *SpoolingAccessLogSink {
+	return &SpoolingAccessLogSink{Store: store, Spool: spool}
+}
+
+// アクセスログを DB に保存し、DB に接続できない場合はスプールに退避する
+// DB に接続できるのに保存に失敗した場合は退避せずにエラーを返す
+func (s *SpoolingAccessLogSink) InsertAccessLogs(entries []entity.AccessLogEntry) error {
+	err := s.Store.InsertAccessLogs(entries)
+	if err == nil {
+		return nil
+	}
+	if s.Store.Ping() == nil {
+		return err
+	}
+
+	if spoolErr := s.Spool.Append(entries); spoolErr != nil {
+		return fmt.Errorf("failed to spool access logs after %v: %w", err, spoolErr)
+	}
+	s.spooled.Add(int64(len(entries)))
+	log.Printf("Database unavailable, spooled %d access logs: %v", len(entries), err)
+	return nil
+}
+
+// スプールしたアクセスログを DB が復旧していれば再送する
+func (s *SpoolingAccessLogSink) Replay() (int, error) {
+	if err := s.Store.Ping(); err != nil {
+		return 0, nil
+	}
+	n, err := s.Spool.Replay(s.Store.InsertAccessLogs)
+	s.replayed.Add(int64(n))
+	if err != nil {
+		return n, fmt.Errorf("failed to replay spooled access logs: %w", err)
+	}
+	if n > 0 {
+		log.Printf("Replayed %d spooled access logs", n)
+	}
+	return n, nil
+}
+
+// 起動時と、ctx が終了するまで一定間隔でスプールの再送を試みる
+func (s *SpoolingAccessLogSink) RunReplayer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Replay(); err != nil {
+			log.Printf("%v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// スプールに退避した件数と再送した件数の累計を返す
+func (s *SpoolingAccessLogSink) Counts() (spooled, replayed int64) {
+	return s.spooled.Load(), s.replayed.Load()
+}
@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/infra"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyAccessLogStore は接続の断絶を切り替えられる service.AccessLogStore のモック
+// 同じ ID のアクセスログは 1 件だけ保存する（DB の一意制約を模倣）
+type flakyAccessLogStore struct {
+	mu         sync.Mutex
+	down       bool
+	failAfter  bool // true の場合は保存した後に 1 回だけエラーを返す
+	rows       map[string]entity.AccessLogEntry
+	insertions int
+}
+
+func (m *flakyAccessLogStore) InsertAccessLogs(entries []entity.AccessLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down {
+		return errors.New("connection refused")
+	}
+	for _, e := range entries {
+		m.rows[e.ID] = e
+		m.insertions++
+	}
+	if m.failAfter {
+		m.failAfter = false
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+func (m *flakyAccessLogStore) Ping() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestSpoolingAccessLogSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access_logs.spool")
+	store := &flakyAccessLogStore{down: true, rows: map[string]entity.AccessLogEntry{}}
+	sink := service.NewSpoolingAccessLogSink(store, infra.NewFileAccessLogSpool(path))
+
+	// DB に接続できない間はスプールに退避してエラーにしない
+	assert.NoError(t, sink.InsertAccessLogs([]entity.AccessLogEntry{{ID: "a", PostalCode: "1000001"}, {ID: "b", PostalCode: "1000002"}}))
+	assert.NoError(t, sink.InsertAccessLogs([]entity.AccessLogEntry{{ID: "c", PostalCode: "1000003"}}))
+	assert.FileExists(t, path)
+
+	// 復旧前は再送しない
+	n, err := sink.Replay()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// 復旧後の再送が途中で失敗しても、次の再送で重複なく保存される
+	store.down = false
+	store.failAfter = true
+	_, err = sink.Replay()
+	assert.Error(t, err)
+
+	n, err = sink.Replay()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Len(t, store.rows, 3)
+	assert.Equal(t, "1000003", store.rows["c"].PostalCode)
+
+	// 再送が終わるとスプールは空になる
+	n, err = sink.Replay()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	_, statErr := os.Stat(path)
+	assert.True(t, errors.Is(statErr, os.ErrNotExist))
+
+	spooled, replayed := sink.Counts()
+	assert.Equal(t, int64(3), spooled)
+	assert.Equal(t, int64(3), replayed)
+}
+
+func TestSpoolingAccessLogSinkReturnsErrorWhenDatabaseIsReachable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access_logs.spool")
+	store := &flakyAccessLogStore{rows: map[string]entity.AccessLogEntry{}, failAfter: true}
+	sink := service.NewSpoolingAccessLogSink(store, infra.NewFileAccessLogSpool(path))
+
+	// DB に接続できる場合の失敗は退避しない
+	assert.Error(t, sink.InsertAccessLogs([]entity.AccessLogEntry{{ID: "a"}}))
+	assert.NoFileExists(t, path)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+)
+
+// バッファが満杯のときの動作
+const (
+	OverflowBlock = "block" // 空きができるまで待つ
+	OverflowDrop  = "drop"  // 破棄して件数を数える
+)
+
+// 停止後に書き込もうとした場合のエラー
+var ErrAccessLogWriterClosed = errors.New("access log writer is closed")
+
+// AccessLogSink はアクセスログをまとめて保存する先
+type AccessLogSink interface {
+	InsertAccessLogs(entries []entity.AccessLogEntry) error
+}
+
+// AccessLogWriterOptions は AccessLogWriter の設定
+type AccessLogWriterOptions struct {
+	BufferSize    int           // メモリに溜めておける件数
+	BatchSize     int           // 1 回の INSERT でまとめる件数
+	FlushInterval time.Duration // バッチが埋まらなくても書き出す間隔
+	Overflow      string        // OverflowBlock または OverflowDrop
+}
+
+// AccessLogWriterStats は AccessLogWriter の累計件数
+type AccessLogWriterStats struct {
+	Enqueued int64 `json:"enqueued"`
+	Dropped  int64 `json:"dropped"`
+	Flushed  int64 `json:"flushed"`
+	Failed   int64 `json:"failed"`
+	Pending  int   `json:"pending"`
+}
+
+// AccessLogWriter はアクセスログをメモリに溜め、件数または一定間隔でまとめて保存する
+type AccessLogWriter struct {
+	sink    AccessLogSink
+	opts    AccessLogWriterOptions
+	entries chan entity.AccessLogEntry
+	closing chan struct{} // Close で閉じる
+	done    chan struct{} // 書き出しを終えたら閉じる
+
+	mu      sync.Mutex // closed と writers の追加を保護
+	closed  bool
+	writers sync.WaitGroup // バッファに追加中の Write
+
+	enqueued, dropped, flushed, failed atomic.Int64
+}
+
+// AccessLogWriter を作成し、書き出しを開始する
+func NewAccessLogWriter(sink AccessLogSink, opts AccessLogWriterOptions) *AccessLogWriter {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+
+	w := &AccessLogWriter{
+		sink:    sink,
+		opts:    opts,
+		entries: make(chan entity.AccessLogEntry, opts.BufferSize),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// アクセスログをバッファに追加する
+// バッファが満杯の場合は設定に応じて待つか、破棄して件数を数える
+// 空きを待っている間に Close された場合は ErrAccessLogWriterClosed を返す
+func (w *AccessLogWriter) Write(entry entity.AccessLogEntry) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrAccessLogWriterClosed
+	}
+	w.writers.Add(1)
+	w.mu.Unlock()
+	defer w.writers.Done()
+
+	if w.opts.Overflow == OverflowBlock {
+		select {
+		case w.entries <- entry:
+			w.enqueued.Add(1)
+			return nil
+		case <-w.closing:
+			return ErrAccessLogWriterClosed
+		}
+	}
+
+	select {
+	case w.entries <- entry:
+		w.enqueued.Add(1)
+	default:
+		w.dropped.Add(1)
+	}
+	return nil
+}
+
+// 新しい書き込みを止め、バッファに残ったアクセスログをすべて書き出す
+// ctx の期限までに書き出しが終わらない場合は ctx のエラーを返す
+func (w *AccessLogWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.closing)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 累計件数を返す
+func (w *AccessLogWriter) Stats() AccessLogWriterStats {
+	return AccessLogWriterStats{
+		Enqueued: w.enqueued.Load(),
+		Dropped:  w.dropped.Load(),
+		Flushed:  w.flushed.Load(),
+		Failed:   w.failed.Load(),
+		Pending:  len(w.entries),
+	}
+}
+
+// バッファからアクセスログを取り出し、件数または一定間隔で書き出す
+func (w *AccessLogWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]entity.AccessLogEntry, 0, w.opts.BatchSize)
+	for {
+		select {
+		case entry := <-w.entries:
+			batch = append(batch, entry)
+			if len(batch) >= w.opts.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		case <-w.closing:
+			// 追加中の Write が終わるのを待ってから、バッファに残ったものをすべて書き出す
+			w.writers.Wait()
+			for {
+				select {
+				case entry := <-w.entries:
+					batch = append(batch, entry)
+					if len(batch) >= w.opts.BatchSize {
+						w.flush(batch)
+						batch = batch[:0]
+					}
+				default:
+					w.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// バッチを保存し、結果を件数に反映する
+func (w *AccessLogWriter) flush(batch []entity.AccessLogEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	err := w.sink.InsertAccessLogs(batch)
+	if err == nil {
+		w.flushed.Add(int64(len(batch)))
+		return
+	}
+	if len(batch) == 1 {
+		w.failed.Add(1)
+		log.Printf("Failed to flush 1 access log: %v", err)
+		return
+	}
+
+	// 不正な 1 件でバッチ全体を失わないよう、1 件ずつ保存し直す
+	failed := 0
+	for _, entry := range batch {
+		if rowErr := w.sink.InsertAccessLogs([]entity.AccessLogEntry{entry}); rowErr != nil {
+			failed++
+			err = rowErr
+			continue
+		}
+		w.flushed.Add(1)
+	}
+	w.failed.Add(int64(failed))
+	if failed > 0 {
+		log.Printf("Failed to flush %d of %d access logs: %v", failed, len(batch), err)
+	}
+}
@@ -0,0 +1,177 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryAccessLogSink は受け取ったバッチを記録する service.AccessLogSink のモック
+type memoryAccessLogSink struct {
+	mu      sync.Mutex
+	batches [][]entity.AccessLogEntry
+	block   chan struct{} // nil でなければ閉じられるまで保存を待つ
+	err     error
+	reject  string // この郵便番号を含むバッチは保存に失敗する
+}
+
+func (m *memoryAccessLogSink) InsertAccessLogs(entries []entity.AccessLogEntry) error {
+	if m.block != nil {
+		<-m.block
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	for _, entry := range entries {
+		if m.reject != "" && entry.PostalCode == m.reject {
+			return errors.New("value too long for postal_code")
+		}
+	}
+	m.batches = append(m.batches, append([]entity.AccessLogEntry(nil), entries...))
+	return nil
+}
+
+func (m *memoryAccessLogSink) batchSizes() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sizes := make([]int, len(m.batches))
+	for i, b := range m.batches {
+		sizes[i] = len(b)
+	}
+	return sizes
+}
+
+func TestAccessLogWriterFlushesBySizeAndOnClose(t *testing.T) {
+	sink := &memoryAccessLogSink{}
+	w := service.NewAccessLogWriter(sink, service.AccessLogWriterOptions{
+		BufferSize: 100, BatchSize: 3, FlushInterval: time.Hour, Overflow: service.OverflowBlock,
+	})
+
+	for i := 0; i < 7; i++ {
+		assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "1000001"}))
+	}
+	assert.NoError(t, w.Close(context.Background()))
+
+	// 3 件ずつ書き出し、残りの 1 件は停止時に書き出す
+	assert.Equal(t, []int{3, 3, 1}, sink.batchSizes())
+	assert.Equal(t, service.AccessLogWriterStats{Enqueued: 7, Flushed: 7}, w.Stats())
+
+	// 停止後は書き込めない
+	assert.ErrorIs(t, w.Write(entity.AccessLogEntry{}), service.ErrAccessLogWriterClosed)
+}
+
+func TestAccessLogWriterFlushesByInterval(t *testing.T) {
+	sink := &memoryAccessLogSink{}
+	w := service.NewAccessLogWriter(sink, service.AccessLogWriterOptions{
+		BufferSize: 100, BatchSize: 100, FlushInterval: 10 * time.Millisecond, Overflow: service.OverflowDrop,
+	})
+	defer w.Close(context.Background())
+
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "1000001"}))
+	assert.Eventually(t, func() bool { return w.Stats().Flushed == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestAccessLogWriterDropsWhenFull(t *testing.T) {
+	sink := &memoryAccessLogSink{block: make(chan struct{})}
+	w := service.NewAccessLogWriter(sink, service.AccessLogWriterOptions{
+		BufferSize: 2, BatchSize: 1, FlushInterval: time.Hour, Overflow: service.OverflowDrop,
+	})
+
+	// 1 件目の保存が止まっている間にバッファ（2 件）を超えて書き込む
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "1"}))
+	assert.Eventually(t, func() bool { return w.Stats().Pending == 0 }, time.Second, time.Millisecond)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "2"}))
+	}
+	close(sink.block)
+	assert.NoError(t, w.Close(context.Background()))
+
+	stats := w.Stats()
+	assert.Equal(t, int64(3), stats.Enqueued)
+	assert.Equal(t, int64(3), stats.Dropped)
+	assert.Equal(t, int64(3), stats.Flushed)
+}
+
+func TestAccessLogWriterCountsFailures(t *testing.T) {
+	sink := &memoryAccessLogSink{err: errors.New("db down")}
+	w := service.NewAccessLogWriter(sink, service.AccessLogWriterOptions{
+		BufferSize: 10, BatchSize: 10, FlushInterval: time.Hour, Overflow: service.OverflowDrop,
+	})
+
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "1000001"}))
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "1000002"}))
+	assert.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, int64(2), w.Stats().Failed)
+}
+
+func TestAccessLogWriterRetriesFailedBatchOneByOne(t *testing.T) {
+	sink := &memoryAccessLogSink{reject: "bad"}
+	w := service.NewAccessLogWriter(sink, service.AccessLogWriterOptions{
+		BufferSize: 10, BatchSize: 10, FlushInterval: time.Hour, Overflow: service.OverflowDrop,
+	})
+
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "1000001"}))
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "bad"}))
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "1000002"}))
+	assert.NoError(t, w.Close(context.Background()))
+
+	// 不正な 1 件だけを失敗として数え、残りは保存する
+	assert.Equal(t, []int{1, 1}, sink.batchSizes())
+	stats := w.Stats()
+	assert.Equal(t, int64(2), stats.Flushed)
+	assert.Equal(t, int64(1), stats.Failed)
+}
+
+func TestAccessLogWriterBlocksWhenFull(t *testing.T) {
+	sink := &memoryAccessLogSink{block: make(chan struct{})}
+	w := service.NewAccessLogWriter(sink, service.AccessLogWriterOptions{
+		BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour, Overflow: service.OverflowBlock,
+	})
+
+	// 1 件目の保存が止まっている間にバッファ（1 件）を埋める
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "1"}))
+	assert.Eventually(t, func() bool { return w.Stats().Pending == 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "2"}))
+
+	// 3 件目は空きができるまで待つ
+	written := make(chan error, 1)
+	go func() { written <- w.Write(entity.AccessLogEntry{PostalCode: "3"}) }()
+	select {
+	case <-written:
+		t.Fatal("Write returned while the buffer was full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(sink.block)
+	assert.NoError(t, <-written)
+	assert.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, service.AccessLogWriterStats{Enqueued: 3, Flushed: 3}, w.Stats())
+}
+
+func TestAccessLogWriterCloseDoesNotHangOnStalledSink(t *testing.T) {
+	sink := &memoryAccessLogSink{block: make(chan struct{})}
+	defer close(sink.block)
+	w := service.NewAccessLogWriter(sink, service.AccessLogWriterOptions{
+		BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour, Overflow: service.OverflowBlock,
+	})
+
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "1"}))
+	assert.Eventually(t, func() bool { return w.Stats().Pending == 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, w.Write(entity.AccessLogEntry{PostalCode: "2"}))
+	written := make(chan error, 1)
+	go func() { written <- w.Write(entity.AccessLogEntry{PostalCode: "3"}) }()
+
+	// 保存が止まっていても ctx の期限で戻り、待っている Write は停止のエラーで戻る
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.Close(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, <-written, service.ErrAccessLogWriterClosed)
+}
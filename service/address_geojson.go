@@ -0,0 +1,73 @@
+package service
+
+import (
+	"math"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/util"
+)
+
+// 住所データを GeoJSON の FeatureCollection として取得
+// 廃止された郵便番号の場合は後継の郵便番号の地点を、プロパティに superseded_by を付けて返す
+func (s *AddressService) GetAddressGeoJSON(postalCode string) (*entity.FeatureCollection, error) {
+	lookupCode, supersededBy := postalCode, ""
+	if succession, ok := s.resolveSuccession(postalCode); ok {
+		lookupCode, supersededBy = succession.SuccessorPostalCode, succession.SuccessorPostalCode
+	}
+
+	locations, err := s.Repo.FetchAddressData(lookupCode)
+	if err != nil {
+		return nil, err
+	}
+	if len(locations) == 0 {
+		return nil, nil
+	}
+
+	return buildFeatureCollection(postalCode, supersededBy, locations), nil
+}
+
+// 地点ごとの Point と重心の Point からなる FeatureCollection を組み立てる
+// supersededBy が空でない場合は各 Feature のプロパティに後継の郵便番号を付ける
+func buildFeatureCollection(postalCode, supersededBy string, locations []entity.AddressLocation) *entity.FeatureCollection {
+	features := make([]entity.Feature, 0, len(locations)+1)
+
+	// 境界ボックス [西端, 南端, 東端, 北端]
+	minLon, minLat := math.Inf(1), math.Inf(1)
+	maxLon, maxLat := math.Inf(-1), math.Inf(-1)
+
+	for _, loc := range locations {
+		features = append(features, entity.NewPointFeature(loc.Lat, loc.Lon, map[string]interface{}{
+			"kind":               "location",
+			"postal_code":        postalCode,
+			"prefecture":         loc.Prefecture,
+			"city":               loc.City,
+			"town":               loc.Town,
+			"tokyo_sta_distance": util.CalculateDistance(util.TokyoStationLat, util.TokyoStationLon, loc.Lat, loc.Lon),
+		}))
+
+		minLon, maxLon = math.Min(minLon, loc.Lon), math.Max(maxLon, loc.Lon)
+		minLat, maxLat = math.Min(minLat, loc.Lat), math.Max(maxLat, loc.Lat)
+	}
+
+	// 全地点の重心
+	center := centroid(locations)
+	features = append(features, entity.NewPointFeature(center.Lat, center.Lon, map[string]interface{}{
+		"kind":               "centroid",
+		"postal_code":        postalCode,
+		"hit_count":          len(locations),
+		"address":            locations[0].Prefecture + locations[0].City + extractCommonTown(locations),
+		"tokyo_sta_distance": util.CalculateDistance(util.TokyoStationLat, util.TokyoStationLon, center.Lat, center.Lon),
+	}))
+
+	if supersededBy != "" {
+		for _, feature := range features {
+			feature.Properties["superseded_by"] = supersededBy
+		}
+	}
+
+	return &entity.FeatureCollection{
+		Type:     entity.GeoJSONFeatureCollection,
+		BBox:     []float64{minLon, minLat, maxLon, maxLat},
+		Features: features,
+	}
+}
@@ -0,0 +1,105 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/dkpcb/finatext_kadai_2/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapAddressRepository は郵便番号ごとの地点を返す entity.AddressRepository のモック
+type mapAddressRepository map[string][]entity.AddressLocation
+
+func (m mapAddressRepository) FetchAddressData(postalCode string) ([]entity.AddressLocation, error) {
+	if postalCode == "error" {
+		return nil, errors.New("mock error")
+	}
+	return m[postalCode], nil
+}
+
+func TestGetAddressGeoJSON(t *testing.T) {
+	repo := mapAddressRepository{
+		"0600002": {
+			{Prefecture: "北海道", City: "札幌市中央区", Town: "北二条西", Lat: 43.0, Lon: 141.0},
+			{Prefecture: "北海道", City: "札幌市中央区", Town: "北二条東", Lat: 43.2, Lon: 141.4},
+		},
+	}
+	s := service.NewAddressService(repo, "")
+
+	collection, err := s.GetAddressGeoJSON("0600002")
+	require.NoError(t, err)
+	require.NotNil(t, collection)
+
+	assert.Equal(t, entity.GeoJSONFeatureCollection, collection.Type)
+	// 境界ボックスは [西端, 南端, 東端, 北端]
+	assert.Equal(t, []float64{141.0, 43.0, 141.4, 43.2}, collection.BBox)
+	require.Len(t, collection.Features, 3)
+
+	// 地点ごとの Feature（座標は [経度, 緯度]）
+	for i, loc := range repo["0600002"] {
+		feature := collection.Features[i]
+		assert.Equal(t, entity.GeoJSONFeature, feature.Type)
+		assert.Equal(t, entity.Geometry{Type: entity.GeoJSONPoint, Coordinates: []float64{loc.Lon, loc.Lat}}, feature.Geometry)
+		assert.Equal(t, "location", feature.Properties["kind"])
+		assert.Equal(t, "0600002", feature.Properties["postal_code"])
+		assert.Equal(t, loc.Prefecture, feature.Properties["prefecture"])
+		assert.Equal(t, loc.City, feature.Properties["city"])
+		assert.Equal(t, loc.Town, feature.Properties["town"])
+		assert.Equal(t, util.CalculateDistance(util.TokyoStationLat, util.TokyoStationLon, loc.Lat, loc.Lon), feature.Properties["tokyo_sta_distance"])
+	}
+
+	// 最後は重心の Feature で、共通の住所と地点の数を持つ
+	centroid := collection.Features[2]
+	assert.Equal(t, entity.GeoJSONPoint, centroid.Geometry.Type)
+	require.Len(t, centroid.Geometry.Coordinates, 2)
+	assert.InDelta(t, 141.2, centroid.Geometry.Coordinates[0], 1e-9)
+	assert.InDelta(t, 43.1, centroid.Geometry.Coordinates[1], 1e-9)
+	assert.Equal(t, "centroid", centroid.Properties["kind"])
+	assert.Equal(t, "0600002", centroid.Properties["postal_code"])
+	assert.Equal(t, 2, centroid.Properties["hit_count"])
+	assert.Equal(t, "北海道札幌市中央区北二条", centroid.Properties["address"])
+	assert.InDelta(t, util.CalculateDistance(util.TokyoStationLat, util.TokyoStationLon, 43.1, 141.2), centroid.Properties["tokyo_sta_distance"], 1e-9)
+}
+
+func TestGetAddressGeoJSONNotFoundAndError(t *testing.T) {
+	s := service.NewAddressService(mapAddressRepository{}, "")
+
+	collection, err := s.GetAddressGeoJSON("9999999")
+	assert.NoError(t, err)
+	assert.Nil(t, collection)
+
+	_, err = s.GetAddressGeoJSON("error")
+	assert.Error(t, err)
+}
+
+func TestGetAddressGeoJSONSuperseded(t *testing.T) {
+	repo := mapAddressRepository{
+		"0600002": {{Prefecture: "北海道", City: "札幌市中央区", Town: "北二条西", Lat: 43.0, Lon: 141.0}},
+	}
+	s := service.NewAddressService(repo, "")
+	s.Successions = service.NewSuccessionService([]entity.PostalCodeSuccession{
+		{Version: "2024-04", RetiredPostalCode: "0600001", SuccessorPostalCode: "0600002"},
+	}, nil)
+
+	// 廃止された郵便番号は後継の地点を返し、すべての Feature に後継の郵便番号を付ける
+	collection, err := s.GetAddressGeoJSON("0600001")
+	require.NoError(t, err)
+	require.NotNil(t, collection)
+	require.Len(t, collection.Features, 2)
+	for _, feature := range collection.Features {
+		assert.Equal(t, "0600001", feature.Properties["postal_code"])
+		assert.Equal(t, "0600002", feature.Properties["superseded_by"])
+	}
+
+	// 現在の郵便番号には付けない
+	collection, err = s.GetAddressGeoJSON("0600002")
+	require.NoError(t, err)
+	require.NotNil(t, collection)
+	for _, feature := range collection.Features {
+		assert.NotContains(t, feature.Properties, "superseded_by")
+	}
+}
@@ -9,12 +9,15 @@ import (
 	"unicode/utf8"
 
 	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/i18n"
 	"github.com/dkpcb/finatext_kadai_2/util"
 )
 
 type AddressService struct {
 	Repo        entity.AddressRepository
 	ExternalAPI string
+	Regions     *RegionService     // 全国地方公共団体コードの参照先（任意）
+	Successions *SuccessionService // 廃止された郵便番号の後継の参照先（任意）
 }
 
 func NewAddressService(repo entity.AddressRepository, externalAPI string) *AddressService {
@@ -25,6 +28,39 @@ func NewAddressService(repo entity.AddressRepository, externalAPI string) *Addre
 }
 
 func (s *AddressService) GetAddress(postalCode string) (*entity.Address, error) {
+	return s.GetLocalizedAddress(postalCode, i18n.Japanese)
+}
+
+// 指定した言語で住所を組み立てて取得
+// 廃止された郵便番号の場合は後継の郵便番号の住所を、後継と廃止の案内を付けて返す
+func (s *AddressService) GetLocalizedAddress(postalCode string, lang i18n.Lang) (*entity.Address, error) {
+	if succession, ok := s.resolveSuccession(postalCode); ok {
+		address, err := s.lookupAddress(succession.SuccessorPostalCode, lang)
+		if err != nil || address == nil {
+			return address, err
+		}
+		address.PostalCode = postalCode
+		address.SupersededBy = succession.SuccessorPostalCode
+		address.Deprecation = i18n.Message(lang, i18n.MsgPostalCodeSuperseded, postalCode, succession.Version, succession.SuccessorPostalCode)
+		return address, nil
+	}
+	return s.lookupAddress(postalCode, lang)
+}
+
+// 廃止された郵便番号の場合は後継の郵便番号を返す
+func (s *AddressService) resolveSuccession(postalCode string) (entity.PostalCodeSuccession, bool) {
+	if s.Successions == nil {
+		return entity.PostalCodeSuccession{}, false
+	}
+	succession, ok := s.Successions.Resolve(postalCode)
+	if ok {
+		log.Printf("postalCode %s was retired in %s, using successor %s", postalCode, succession.Version, succession.SuccessorPostalCode)
+	}
+	return succession, ok
+}
+
+// 外部 API のデータから住所を組み立てる
+func (s *AddressService) lookupAddress(postalCode string, lang i18n.Lang) (*entity.Address, error) {
 	log.Printf("Starting GetAddress for postalCode: %s", postalCode)
 
 	// 外部 API からデータを取得
@@ -46,7 +82,7 @@ func (s *AddressService) GetAddress(postalCode string) (*entity.Address, error)
 	commonPrefecture := locations[0].Prefecture
 	commonCity := locations[0].City
 	commonTown := extractCommonTown(locations)
-	commonAddress := commonPrefecture + commonCity + commonTown
+	commonAddress := i18n.FormatAddress(lang, commonPrefecture, commonCity, commonTown)
 
 	log.Printf("Constructed common address: %s", commonAddress)
 
@@ -65,6 +101,14 @@ func (s *AddressService) GetAddress(postalCode string) (*entity.Address, error)
 	log.Printf("Final max distance for postalCode %s: %f km", postalCode, maxDistance)
 
 	address := entity.NewAddress(postalCode, len(locations), commonAddress, maxDistance)
+
+	// ローカルデータセットから全国地方公共団体コードを付与
+	if s.Regions != nil {
+		if code, ok := s.Regions.MunicipalityCodeOf(postalCode); ok {
+			address.PrefectureCode = code[:2]
+			address.MunicipalityCode, _ = util.JISCodeWithCheckDigit(code)
+		}
+	}
 	log.Printf("Constructed address entity: %+v", address)
 
 	return address, nil
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+)
+
+type DatasetService struct {
+	Repo entity.PostalDatasetRepository
+}
+
+// 新しい DatasetService を作成
+func NewDatasetService(repo entity.PostalDatasetRepository) *DatasetService {
+	return &DatasetService{Repo: repo}
+}
+
+// 全件データを取り込み、前回のデータとの差分を変更履歴に記録する
+// 同じチェックサムのデータが取り込み済みの場合は何もせず false を返す
+func (s *DatasetService) ImportSnapshot(version, source, checksum string, records []entity.PostalRecord) (bool, error) {
+	versions, err := s.Repo.ListVersions()
+	if err != nil {
+		return false, err
+	}
+	if findChecksum(versions, checksum) {
+		return false, nil
+	}
+
+	next := groupByPostalCode(records)
+
+	// 初回の取り込みは比較対象がないため変更履歴を記録しない
+	var changes []entity.PostalCodeChange
+	var successions []entity.PostalCodeSuccession
+	if len(versions) > 0 {
+		records, err := s.Repo.LoadRecords(nil)
+		if err != nil {
+			return false, err
+		}
+		current := groupByPostalCode(records)
+		changes = diffRecords(version, current, next)
+		successions = findSuccessions(version, changes, current, next)
+	}
+
+	v := entity.DatasetVersion{
+		Version:     version,
+		Kind:        entity.DatasetKindFull,
+		Source:      truncateSource(source),
+		Checksum:    checksum,
+		RecordCount: len(records),
+		ImportedAt:  time.Now(),
+	}
+	update := entity.DatasetUpdate{
+		Version:     v,
+		Records:     next,
+		ReplaceAll:  true,
+		Changes:     changes,
+		Successions: successions,
+	}
+	if err := s.Repo.SaveVersion(update); err != nil {
+		return false, fmt.Errorf("failed to import postal dataset %s: %w", version, err)
+	}
+	return true, nil
+}
+
+// 月次の追加・削除データを現在のデータに適用し、変更履歴に記録する
+// 取り込み済み、または全件データに含まれる古いバージョンの場合は何もせず false を返す
+func (s *DatasetService) ApplyDiff(version, source, checksum string, added, deleted []entity.PostalRecord) (bool, error) {
+	versions, err := s.Repo.ListVersions()
+	if err != nil {
+		return false, err
+	}
+	if findChecksum(versions, checksum) {
+		return false, nil
+	}
+	for _, v := range versions {
+		if v.Kind == entity.DatasetKindFull && v.Version >= version {
+			return false, nil
+		}
+	}
+
+	// 影響を受ける郵便番号の現在のデータを取得
+	affected := make(map[string]struct{})
+	for _, r := range append(append([]entity.PostalRecord{}, deleted...), added...) {
+		affected[r.PostalCode] = struct{}{}
+	}
+	codes := sortedKeys(affected)
+	records, err := s.Repo.LoadRecords(codes)
+	if err != nil {
+		return false, err
+	}
+	current := groupByPostalCode(records)
+
+	// 削除データを取り除いてから追加データを加える
+	removed := make(map[recordKey]struct{}, len(deleted))
+	for _, r := range deleted {
+		removed[keyOf(r)] = struct{}{}
+	}
+	next := make(map[string][]entity.PostalRecord, len(codes))
+	for _, code := range codes {
+		next[code] = []entity.PostalRecord{}
+		for _, r := range current[code] {
+			if _, ok := removed[keyOf(r)]; !ok {
+				next[code] = append(next[code], r)
+			}
+		}
+	}
+	for _, r := range added {
+		exists := false
+		for _, existing := range next[r.PostalCode] {
+			if keyOf(existing) == keyOf(r) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			next[r.PostalCode] = append(next[r.PostalCode], r)
+		}
+	}
+
+	v := entity.DatasetVersion{
+		Version:     version,
+		Kind:        entity.DatasetKindDiff,
+		Source:      truncateSource(source),
+		Checksum:    checksum,
+		RecordCount: len(added) + len(deleted),
+		ImportedAt:  time.Now(),
+	}
+	changes := diffRecords(version, current, next)
+	update := entity.DatasetUpdate{
+		Version:     v,
+		Records:     next,
+		Changes:     changes,
+		Successions: findSuccessions(version, changes, current, next),
+	}
+	if err := s.Repo.SaveVersion(update); err != nil {
+		return false, fmt.Errorf("failed to apply postal dataset diff %s: %w", version, err)
+	}
+	return true, nil
+}
+
+// 現在の郵便番号データをすべて返す
+func (s *DatasetService) Snapshot() ([]entity.PostalRecord, error) {
+	return s.Repo.LoadRecords(nil)
+}
+
+// 廃止された郵便番号と後継の対応をすべて返す
+func (s *DatasetService) Successions() ([]entity.PostalCodeSuccession, error) {
+	successions, err := s.Repo.ListSuccessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch postal code successions: %w", err)
+	}
+	return successions, nil
+}
+
+// 指定したバージョン以降の変更履歴を種別ごとにまとめて返す
+func (s *DatasetService) Changes(since string) (*entity.PostalCodeChanges, error) {
+	changes, err := s.Repo.ChangesSince(since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch postal code changes: %w", err)
+	}
+	versions, err := s.Repo.ListVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &entity.PostalCodeChanges{
+		Since:    since,
+		Added:    []entity.PostalCodeChange{},
+		Removed:  []entity.PostalCodeChange{},
+		Modified: []entity.PostalCodeChange{},
+	}
+	if len(versions) > 0 {
+		result.LatestVersion = versions[len(versions)-1].Version
+	}
+	for _, change := range changes {
+		switch change.ChangeType {
+		case entity.ChangeAdded:
+			result.Added = append(result.Added, change)
+		case entity.ChangeRemoved:
+			result.Removed = append(result.Removed, change)
+		case entity.ChangeModified:
+			result.Modified = append(result.Modified, change)
+		}
+	}
+	return result, nil
+}
+
+// recordKey は同じ住所かどうかを判定するためのキー
+type recordKey struct {
+	postalCode, jisCode, town string
+}
+
+func keyOf(r entity.PostalRecord) recordKey {
+	return recordKey{postalCode: r.PostalCode, jisCode: r.JISCode, town: r.Town}
+}
+
+// 郵便番号ごとにレコードをまとめる
+func groupByPostalCode(records []entity.PostalRecord) map[string][]entity.PostalRecord {
+	grouped := make(map[string][]entity.PostalRecord)
+	for _, r := range records {
+		grouped[r.PostalCode] = append(grouped[r.PostalCode], r)
+	}
+	return grouped
+}
+
+// 郵便番号ごとに住所の集合を比較して変更履歴を作成
+func diffRecords(version string, current, next map[string][]entity.PostalRecord) []entity.PostalCodeChange {
+	codes := make(map[string]struct{}, len(current)+len(next))
+	for code := range current {
+		codes[code] = struct{}{}
+	}
+	for code := range next {
+		codes[code] = struct{}{}
+	}
+
+	var changes []entity.PostalCodeChange
+	for _, code := range sortedKeys(codes) {
+		oldAddresses := addressesOf(current[code])
+		newAddresses := addressesOf(next[code])
+
+		change := entity.PostalCodeChange{
+			Version:      version,
+			PostalCode:   code,
+			OldAddresses: oldAddresses,
+			NewAddresses: newAddresses,
+		}
+		switch {
+		case len(oldAddresses) == 0 && len(newAddresses) == 0:
+			continue
+		case len(oldAddresses) == 0:
+			change.ChangeType = entity.ChangeAdded
+		case len(newAddresses) == 0:
+			change.ChangeType = entity.ChangeRemoved
+		case !equalStrings(oldAddresses, newAddresses):
+			change.ChangeType = entity.ChangeModified
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// 廃止された郵便番号ごとに、同じバージョンで追加・変更された郵便番号から後継を探す
+// 住所表記が一致するレコード、または同じ都道府県で町域名が一致するレコードが最も多い郵便番号を後継とする
+func findSuccessions(version string, changes []entity.PostalCodeChange, current, next map[string][]entity.PostalRecord) []entity.PostalCodeSuccession {
+	var candidates []string
+	for _, change := range changes {
+		if change.ChangeType != entity.ChangeRemoved {
+			candidates = append(candidates, change.PostalCode)
+		}
+	}
+
+	var successions []entity.PostalCodeSuccession
+	for _, change := range changes {
+		if change.ChangeType != entity.ChangeRemoved {
+			continue
+		}
+		best, bestScore := "", 0
+		for _, code := range candidates {
+			if score := successionScore(current[change.PostalCode], next[code]); score > bestScore {
+				best, bestScore = code, score
+			}
+		}
+		if best != "" {
+			successions = append(successions, entity.PostalCodeSuccession{
+				Version:             version,
+				RetiredPostalCode:   change.PostalCode,
+				SuccessorPostalCode: best,
+			})
+		}
+	}
+	return successions
+}
+
+// 廃止前のレコードのうち、後継候補のレコードに引き継がれたものの数を返す
+func successionScore(retired, candidate []entity.PostalRecord) int {
+	score := 0
+	for _, r := range retired {
+		for _, c := range candidate {
+			sameAddress := r.Prefecture+r.City+r.Town == c.Prefecture+c.City+c.Town
+			sameTown := r.Town != "" && r.Prefecture == c.Prefecture && r.Town == c.Town
+			if sameAddress || sameTown {
+				score++
+				break
+			}
+		}
+	}
+	return score
+}
+
+// レコードの住所表記を重複なく昇順で返す
+func addressesOf(records []entity.PostalRecord) []string {
+	set := make(map[string]struct{}, len(records))
+	for _, r := range records {
+		set[r.Prefecture+r.City+r.Town] = struct{}{}
+	}
+	return sortedKeys(set)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// 同じチェックサムのバージョンが取り込み済みかを判定
+func findChecksum(versions []entity.DatasetVersion, checksum string) bool {
+	for _, v := range versions {
+		if v.Checksum == checksum {
+			return true
+		}
+	}
+	return false
+}
+
+// 取り込み元をカラムの長さに切り詰める（差分のファイルが多い場合など。切り詰めた場合は末尾を … にする）
+func truncateSource(source string) string {
+	if utf8.RuneCountInString(source) <= entity.DatasetSourceMaxLength {
+		return source
+	}
+	return string([]rune(source)[:entity.DatasetSourceMaxLength-1]) + "…"
+}
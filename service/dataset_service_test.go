@@ -0,0 +1,195 @@
+package service_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryDatasetRepository は entity.PostalDatasetRepository のインメモリ実装
+type memoryDatasetRepository struct {
+	versions    []entity.DatasetVersion
+	records     map[string][]entity.PostalRecord
+	changes     []entity.PostalCodeChange
+	successions []entity.PostalCodeSuccession
+}
+
+func (m *memoryDatasetRepository) ListVersions() ([]entity.DatasetVersion, error) {
+	return m.versions, nil
+}
+
+func (m *memoryDatasetRepository) LoadRecords(postalCodes []string) ([]entity.PostalRecord, error) {
+	var records []entity.PostalRecord
+	if postalCodes == nil {
+		for _, list := range m.records {
+			records = append(records, list...)
+		}
+		return records, nil
+	}
+	for _, code := range postalCodes {
+		records = append(records, m.records[code]...)
+	}
+	return records, nil
+}
+
+func (m *memoryDatasetRepository) SaveVersion(update entity.DatasetUpdate) error {
+	if update.ReplaceAll || m.records == nil {
+		m.records = map[string][]entity.PostalRecord{}
+	}
+	for code, list := range update.Records {
+		m.records[code] = list
+	}
+	m.versions = append(m.versions, update.Version)
+	m.changes = append(m.changes, update.Changes...)
+	m.successions = append(m.successions, update.Successions...)
+	return nil
+}
+
+func (m *memoryDatasetRepository) ChangesSince(version string) ([]entity.PostalCodeChange, error) {
+	var changes []entity.PostalCodeChange
+	for _, c := range m.changes {
+		if c.Version >= version {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func (m *memoryDatasetRepository) ListSuccessions() ([]entity.PostalCodeSuccession, error) {
+	return m.successions, nil
+}
+
+func gifu(postalCode, town string) entity.PostalRecord {
+	return entity.PostalRecord{PostalCode: postalCode, JISCode: "21201", Prefecture: "岐阜県", City: "岐阜市", Town: town}
+}
+
+func TestDatasetService(t *testing.T) {
+	repo := &memoryDatasetRepository{}
+	s := service.NewDatasetService(repo)
+
+	// 初回の全件取り込みは変更履歴を記録しない
+	imported, err := s.ImportSnapshot("2024-01", "dataset.csv", "sum1", []entity.PostalRecord{
+		gifu("5016121", "柳津町"),
+		gifu("5016122", "柳津町本郷"),
+		gifu("5016123", "柳津町東塚"),
+	})
+	assert.NoError(t, err)
+	assert.True(t, imported)
+	assert.Empty(t, repo.changes)
+
+	// 同じチェックサムは取り込まない
+	imported, err = s.ImportSnapshot("2024-01", "dataset.csv", "sum1", nil)
+	assert.NoError(t, err)
+	assert.False(t, imported)
+
+	// 月次差分: 5016122 を削除、5016123 の町域を変更、5016124 を追加
+	applied, err := s.ApplyDiff("2024-02", "ADD_2402.CSV DEL_2402.CSV", "sum2",
+		[]entity.PostalRecord{gifu("5016123", "柳津町北塚"), gifu("5016124", "柳津町丸野")},
+		[]entity.PostalRecord{gifu("5016122", "柳津町本郷"), gifu("5016123", "柳津町東塚")},
+	)
+	assert.NoError(t, err)
+	assert.True(t, applied)
+
+	// 全件データより古い差分は適用しない
+	applied, err = s.ApplyDiff("2023-12", "ADD_2312.CSV", "sum0", []entity.PostalRecord{gifu("5019999", "")}, nil)
+	assert.NoError(t, err)
+	assert.False(t, applied)
+
+	changes, err := s.Changes("2024-02")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-02", changes.LatestVersion)
+	assert.Equal(t, []entity.PostalCodeChange{
+		{Version: "2024-02", PostalCode: "5016124", ChangeType: entity.ChangeAdded, OldAddresses: []string{}, NewAddresses: []string{"岐阜県岐阜市柳津町丸野"}},
+	}, changes.Added)
+	assert.Equal(t, []entity.PostalCodeChange{
+		{Version: "2024-02", PostalCode: "5016122", ChangeType: entity.ChangeRemoved, OldAddresses: []string{"岐阜県岐阜市柳津町本郷"}, NewAddresses: []string{}},
+	}, changes.Removed)
+	assert.Equal(t, []entity.PostalCodeChange{
+		{Version: "2024-02", PostalCode: "5016123", ChangeType: entity.ChangeModified, OldAddresses: []string{"岐阜県岐阜市柳津町東塚"}, NewAddresses: []string{"岐阜県岐阜市柳津町北塚"}},
+	}, changes.Modified)
+
+	snapshot, err := s.Snapshot()
+	assert.NoError(t, err)
+	assert.Len(t, snapshot, 3)
+
+	// 以降の年月を指定すると変更はない
+	changes, err = s.Changes("2024-03")
+	assert.NoError(t, err)
+	assert.Empty(t, changes.Added)
+	assert.Empty(t, changes.Removed)
+	assert.Empty(t, changes.Modified)
+}
+
+func TestDatasetServiceSuccessions(t *testing.T) {
+	repo := &memoryDatasetRepository{}
+	s := service.NewDatasetService(repo)
+
+	_, err := s.ImportSnapshot("2024-01", "dataset.csv", "sum1", []entity.PostalRecord{
+		gifu("5016121", "柳津町"),
+		gifu("5016122", "柳津町本郷"),
+		gifu("5016123", "柳津町東塚"),
+	})
+	assert.NoError(t, err)
+
+	// 5016122 を廃止し、同じ町域を 5016125 に付け替える
+	_, err = s.ApplyDiff("2024-02", "ADD_2402.CSV DEL_2402.CSV", "sum2",
+		[]entity.PostalRecord{gifu("5016125", "柳津町本郷")},
+		[]entity.PostalRecord{gifu("5016122", "柳津町本郷")},
+	)
+	assert.NoError(t, err)
+
+	// 全件データで 5016125 を廃止し、町域を 5016126 に移す
+	_, err = s.ImportSnapshot("2024-03", "dataset.csv", "sum3", []entity.PostalRecord{
+		gifu("5016121", "柳津町"),
+		gifu("5016123", "柳津町東塚"),
+		gifu("5016126", "柳津町本郷"),
+	})
+	assert.NoError(t, err)
+
+	successions, err := s.Successions()
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.PostalCodeSuccession{
+		{Version: "2024-02", RetiredPostalCode: "5016122", SuccessorPostalCode: "5016125"},
+		{Version: "2024-03", RetiredPostalCode: "5016125", SuccessorPostalCode: "5016126"},
+	}, successions)
+
+	snapshot, err := s.Snapshot()
+	assert.NoError(t, err)
+	resolver := service.NewSuccessionService(successions, snapshot)
+
+	// 後継をたどって現在の郵便番号を返し、廃止されたバージョンは最初のものを返す
+	succession, ok := resolver.Resolve("5016122")
+	assert.True(t, ok)
+	assert.Equal(t, entity.PostalCodeSuccession{Version: "2024-02", RetiredPostalCode: "5016122", SuccessorPostalCode: "5016126"}, succession)
+
+	// 現在の郵便番号は廃止扱いにしない
+	_, ok = resolver.Resolve("5016123")
+	assert.False(t, ok)
+}
+
+func TestDatasetServiceTruncatesSource(t *testing.T) {
+	repo := &memoryDatasetRepository{}
+	s := service.NewDatasetService(repo)
+
+	// 取り込み元はカラムの長さに切り詰める
+	var names []string
+	for i := 0; i < 30; i++ {
+		names = append(names, fmt.Sprintf("ADD_%04d.CSV", 2400+i))
+	}
+	source := strings.Join(names, ",")
+	applied, err := s.ApplyDiff("2024-02", source, "sum", []entity.PostalRecord{gifu("5016124", "柳津町丸野")}, nil)
+	assert.NoError(t, err)
+	assert.True(t, applied)
+
+	require.Len(t, repo.versions, 1)
+	saved := repo.versions[0].Source
+	assert.Equal(t, entity.DatasetSourceMaxLength, utf8.RuneCountInString(saved))
+	assert.True(t, strings.HasPrefix(source, strings.TrimSuffix(saved, "…")))
+	assert.True(t, strings.HasSuffix(saved, "…"))
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/util"
+)
+
+// ErrAddressNotFound は郵便番号に該当する住所が存在しない場合のエラー
+var ErrAddressNotFound = errors.New("address not found")
+
+// ErrInvalidPostalCode は郵便番号が 7 桁の数字でない場合のエラー
+var ErrInvalidPostalCode = errors.New("postal code must be 7 digits")
+
+// 座標のキャッシュに保持する郵便番号の最大数
+const maxPointCacheEntries = 10000
+
+type DistanceService struct {
+	Repo        entity.AddressRepository
+	Concurrency int
+	cache       *pointCache
+}
+
+// 新しい DistanceService を作成
+func NewDistanceService(repo entity.AddressRepository, concurrency int, cacheTTL time.Duration) *DistanceService {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &DistanceService{
+		Repo:        repo,
+		Concurrency: concurrency,
+		cache:       newPointCache(cacheTTL),
+	}
+}
+
+// 出発地と目的地の全組み合わせの距離を計算
+func (s *DistanceService) BuildMatrix(origins, destinations []string) *entity.DistanceMatrix {
+	// 重複を除いた郵便番号ごとに一度だけ座標を解決
+	points := s.resolveAll(uniqueCodes(origins, destinations))
+
+	rows := make([][]entity.DistanceCell, len(origins))
+	for i, origin := range origins {
+		rows[i] = make([]entity.DistanceCell, len(destinations))
+		for j, destination := range destinations {
+			rows[i][j] = buildCell(origin, destination, points)
+		}
+	}
+
+	return &entity.DistanceMatrix{
+		Origins:      origins,
+		Destinations: destinations,
+		Rows:         rows,
+	}
+}
+
+// 郵便番号の代表座標（全地点の重心）を取得
+// 郵便番号は外部 API の URL に埋め込むため、7 桁の数字でない場合は問い合わせない
+func (s *DistanceService) ResolvePoint(postalCode string) (*entity.GeoPoint, error) {
+	if !isPostalCode(postalCode) {
+		return nil, ErrInvalidPostalCode
+	}
+	if point, ok := s.cache.get(postalCode); ok {
+		return point, nil
+	}
+
+	locations, err := s.Repo.FetchAddressData(postalCode)
+	if err != nil {
+		// 一時的なエラーはキャッシュしない
+		return nil, err
+	}
+
+	// 存在しない郵便番号はキャッシュしない（任意の入力でキャッシュが埋まらないようにする）
+	point := centroid(locations)
+	if point == nil {
+		return nil, ErrAddressNotFound
+	}
+	s.cache.set(postalCode, point)
+	return point, nil
+}
+
+// 7 桁の数字か
+func isPostalCode(code string) bool {
+	if len(code) != 7 {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+type pointResult struct {
+	point *entity.GeoPoint
+	err   error
+}
+
+// 同時実行数を制限しながら郵便番号の座標を解決
+func (s *DistanceService) resolveAll(codes []string) map[string]pointResult {
+	results := make(map[string]pointResult, len(codes))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.Concurrency)
+
+	for _, code := range codes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(code string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			point, err := s.ResolvePoint(code)
+			mu.Lock()
+			results[code] = pointResult{point: point, err: err}
+			mu.Unlock()
+		}(code)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// 1 セル分の距離を計算し、解決に失敗した場合はエラーを設定
+func buildCell(origin, destination string, points map[string]pointResult) entity.DistanceCell {
+	cell := entity.DistanceCell{Origin: origin, Destination: destination}
+
+	from, to := points[origin], points[destination]
+	if from.err != nil {
+		cell.Error = fmt.Sprintf("origin %s: %v", origin, from.err)
+		return cell
+	}
+	if to.err != nil {
+		cell.Error = fmt.Sprintf("destination %s: %v", destination, to.err)
+		return cell
+	}
+
+	distance := util.CalculateDistance(from.point.Lat, from.point.Lon, to.point.Lat, to.point.Lon)
+	cell.DistanceKm = &distance
+	return cell
+}
+
+// 出現順を保ったまま郵便番号の重複を除去
+func uniqueCodes(lists ...[]string) []string {
+	seen := make(map[string]struct{})
+	var codes []string
+	for _, list := range lists {
+		for _, code := range list {
+			if _, ok := seen[code]; ok {
+				continue
+			}
+			seen[code] = struct{}{}
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// 地点の重心を計算（地点がない場合は nil）
+func centroid(locations []entity.AddressLocation) *entity.GeoPoint {
+	if len(locations) == 0 {
+		return nil
+	}
+
+	var lat, lon float64
+	for _, loc := range locations {
+		lat += loc.Lat
+		lon += loc.Lon
+	}
+	n := float64(len(locations))
+	return &entity.GeoPoint{Lat: lat / n, Lon: lon / n}
+}
+
+// pointCache は郵便番号ごとの座標を TTL 付きで保持するキャッシュ
+// 件数が上限に達した場合は期限切れの座標を削除し、それでも空かなければ任意の座標を 1 件削除する
+type pointCache struct {
+	mu         sync.RWMutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]pointCacheEntry
+}
+
+type pointCacheEntry struct {
+	point     *entity.GeoPoint
+	expiresAt time.Time
+}
+
+func newPointCache(ttl time.Duration) *pointCache {
+	return &pointCache{ttl: ttl, maxEntries: maxPointCacheEntries, entries: make(map[string]pointCacheEntry)}
+}
+
+// キャッシュから座標を取得
+func (c *pointCache) get(postalCode string) (*entity.GeoPoint, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[postalCode]
+	if !ok || c.expired(entry, time.Now()) {
+		return nil, false
+	}
+	return entry.point, true
+}
+
+func (c *pointCache) set(postalCode string, point *entity.GeoPoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.entries[postalCode]; !ok && len(c.entries) >= c.maxEntries {
+		for code, entry := range c.entries {
+			if c.expired(entry, now) {
+				delete(c.entries, code)
+			}
+		}
+		for code := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, code)
+		}
+	}
+	c.entries[postalCode] = pointCacheEntry{point: point, expiresAt: now.Add(c.ttl)}
+}
+
+// 期限切れか（TTL が 0 以下の場合は期限なし）
+func (c *pointCache) expired(entry pointCacheEntry, now time.Time) bool {
+	return c.ttl > 0 && now.After(entry.expiresAt)
+}
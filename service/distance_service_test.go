@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingAddressRepository は呼び出し回数を記録する entity.AddressRepository のモック
+type countingAddressRepository struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (m *countingAddressRepository) FetchAddressData(postalCode string) ([]entity.AddressLocation, error) {
+	m.mu.Lock()
+	m.calls[postalCode]++
+	m.mu.Unlock()
+
+	switch postalCode {
+	case "1000005":
+		return []entity.AddressLocation{{Prefecture: "東京都", City: "千代田区", Town: "丸の内", Lat: 35.6809591, Lon: 139.7673068}}, nil
+	case "5300001":
+		return []entity.AddressLocation{{Prefecture: "大阪府", City: "大阪市北区", Town: "梅田", Lat: 34.702485, Lon: 135.495951}}, nil
+	case "9999999":
+		return nil, nil
+	}
+	return nil, errors.New("mock error")
+}
+
+func TestDistanceService_BuildMatrix(t *testing.T) {
+	repo := &countingAddressRepository{calls: map[string]int{}}
+	s := service.NewDistanceService(repo, 2, time.Minute)
+
+	origins := []string{"1000005", "5300001", "1000005"}
+	destinations := []string{"5300001", "9999999", "8888888"}
+	matrix := s.BuildMatrix(origins, destinations)
+
+	assert.Equal(t, origins, matrix.Origins)
+	assert.Len(t, matrix.Rows, 3)
+	assert.Len(t, matrix.Rows[0], 3)
+
+	// 正常に解決できたセル
+	if assert.NotNil(t, matrix.Rows[0][0].DistanceKm) {
+		assert.InDelta(t, 403.3, *matrix.Rows[0][0].DistanceKm, 1.0)
+	}
+	if assert.NotNil(t, matrix.Rows[1][0].DistanceKm) {
+		assert.Equal(t, 0.0, *matrix.Rows[1][0].DistanceKm)
+	}
+
+	// 解決できないセルはエラーのみを持つ
+	assert.Nil(t, matrix.Rows[0][1].DistanceKm)
+	assert.Equal(t, "destination 9999999: address not found", matrix.Rows[0][1].Error)
+	assert.Equal(t, "destination 8888888: mock error", matrix.Rows[2][2].Error)
+
+	// 重複した郵便番号は一度だけ解決される
+	for code, n := range repo.calls {
+		assert.Equal(t, 1, n, code)
+	}
+
+	// 2 回目はキャッシュから解決され、存在しない郵便番号とエラーだけが再取得される
+	s.BuildMatrix([]string{"1000005"}, []string{"9999999", "8888888"})
+	assert.Equal(t, 1, repo.calls["1000005"])
+	assert.Equal(t, 2, repo.calls["9999999"])
+	assert.Equal(t, 2, repo.calls["8888888"])
+}
+
+func TestDistanceService_ResolvePointInvalidPostalCode(t *testing.T) {
+	repo := &countingAddressRepository{calls: map[string]int{}}
+	s := service.NewDistanceService(repo, 1, time.Minute)
+
+	// 7 桁の数字でない郵便番号は外部 API に問い合わせない
+	for _, code := range []string{"", "100000", "10000050", "100000a", "../1000", "１０００００５"} {
+		_, err := s.ResolvePoint(code)
+		assert.ErrorIs(t, err, service.ErrInvalidPostalCode, code)
+	}
+	assert.Empty(t, repo.calls)
+}
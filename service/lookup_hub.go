@@ -0,0 +1,119 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+)
+
+// LookupHub は住所検索の結果を購読者に配信するプロセス内の pub/sub
+// 購読者ごとにバッファを持ち、バッファが溢れた（受信が追いつかない）購読者は切断する
+type LookupHub struct {
+	bufferSize int
+
+	mu          sync.Mutex
+	subscribers map[*LookupSubscription]struct{}
+	closed      bool
+
+	published, evicted atomic.Int64
+}
+
+// LookupSubscription は LookupHub の購読
+type LookupSubscription struct {
+	hub     *LookupHub
+	prefix  string
+	events  chan entity.LookupEvent
+	evicted atomic.Bool
+}
+
+// 購読者ごとのバッファの件数を指定して LookupHub を作成
+func NewLookupHub(bufferSize int) *LookupHub {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &LookupHub{bufferSize: bufferSize, subscribers: make(map[*LookupSubscription]struct{})}
+}
+
+// 郵便番号が prefix で始まる検索結果を購読する（空の場合はすべて）
+func (h *LookupHub) Subscribe(prefix string) *LookupSubscription {
+	sub := &LookupSubscription{hub: h, prefix: prefix, events: make(chan entity.LookupEvent, h.bufferSize)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(sub.events)
+		return sub
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// 検索結果を購読者に配信する（待たずに返る）
+func (h *LookupHub) Publish(event entity.LookupEvent) {
+	h.published.Add(1)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if !strings.HasPrefix(event.PostalCode, sub.prefix) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// 受信が追いつかない購読者は切断する
+			sub.evicted.Store(true)
+			h.remove(sub)
+			h.evicted.Add(1)
+		}
+	}
+}
+
+// すべての購読を終了し、以降の購読はすぐに終了させる（シャットダウン時に配信中の接続を閉じるため）
+func (h *LookupHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
+
+// 購読者の数を返す
+func (h *LookupHub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+// 配信した件数と切断した購読者の数の累計を返す
+func (h *LookupHub) Counts() (published, evicted int64) {
+	return h.published.Load(), h.evicted.Load()
+}
+
+// 購読者を取り除き、チャネルを閉じる（mu を保持して呼び出す）
+func (h *LookupHub) remove(sub *LookupSubscription) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	close(sub.events)
+}
+
+// 検索結果を受け取るチャネル（購読の終了時に閉じる）
+func (s *LookupSubscription) Events() <-chan entity.LookupEvent {
+	return s.events
+}
+
+// 受信が追いつかずに切断されたかを返す
+func (s *LookupSubscription) Evicted() bool {
+	return s.evicted.Load()
+}
+
+// 購読を終了する（複数回呼び出してもよい）
+func (s *LookupSubscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
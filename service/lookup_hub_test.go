@@ -0,0 +1,78 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupHubFiltersByPrefix(t *testing.T) {
+	hub := service.NewLookupHub(10)
+	all := hub.Subscribe("")
+	defer all.Close()
+	tokyo := hub.Subscribe("100")
+	defer tokyo.Close()
+
+	hub.Publish(entity.LookupEvent{PostalCode: "1000001"})
+	hub.Publish(entity.LookupEvent{PostalCode: "5016121"})
+
+	assert.Len(t, all.Events(), 2)
+	if assert.Len(t, tokyo.Events(), 1) {
+		assert.Equal(t, "1000001", (<-tokyo.Events()).PostalCode)
+	}
+}
+
+func TestLookupHubEvictsSlowSubscriber(t *testing.T) {
+	hub := service.NewLookupHub(2)
+	slow := hub.Subscribe("")
+	fast := hub.Subscribe("")
+	defer fast.Close()
+
+	for i := 0; i < 3; i++ {
+		hub.Publish(entity.LookupEvent{PostalCode: "1000001"})
+		<-fast.Events()
+	}
+
+	assert.True(t, slow.Evicted())
+	assert.False(t, fast.Evicted())
+	assert.Equal(t, 1, hub.Subscribers())
+
+	// バッファに残っていた分を受け取った後にチャネルが閉じる
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	assert.Equal(t, 2, received)
+
+	published, evicted := hub.Counts()
+	assert.Equal(t, int64(3), published)
+	assert.Equal(t, int64(1), evicted)
+}
+
+func TestLookupSubscriptionClose(t *testing.T) {
+	hub := service.NewLookupHub(1)
+	sub := hub.Subscribe("")
+
+	sub.Close()
+	sub.Close()
+	hub.Publish(entity.LookupEvent{PostalCode: "1000001"})
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	assert.False(t, sub.Evicted())
+	assert.Equal(t, 0, hub.Subscribers())
+}
+
+func TestLookupHubClose(t *testing.T) {
+	hub := service.NewLookupHub(1)
+	sub := hub.Subscribe("")
+
+	hub.Close()
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	_, ok = <-hub.Subscribe("").Events()
+	assert.False(t, ok)
+}
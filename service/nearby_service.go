@@ -0,0 +1,98 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/util"
+)
+
+// 空間インデックスのグリッドの一辺 [度]（約 5km）
+const nearbyGridCellSize = 0.05
+
+type NearbyService struct {
+	codes     []string
+	addresses map[string]entity.NearbyAddress
+	index     *util.GridIndex
+}
+
+// ローカルデータセットから空間インデックスを構築して NearbyService を作成
+func NewNearbyService(records []entity.PostalRecord) *NearbyService {
+	// 郵便番号ごとに地点をまとめる（座標のない行は除外）
+	grouped := make(map[string][]entity.AddressLocation)
+	for _, r := range records {
+		if r.Lat == 0 && r.Lon == 0 {
+			continue
+		}
+		grouped[r.PostalCode] = append(grouped[r.PostalCode], r.Location())
+	}
+
+	codes := make([]string, 0, len(grouped))
+	for code := range grouped {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	// 郵便番号の重心をインデックスに登録
+	addresses := make(map[string]entity.NearbyAddress, len(codes))
+	points := make([]util.IndexedPoint, 0, len(codes))
+	for i, code := range codes {
+		locations := grouped[code]
+		center := centroid(locations)
+		addresses[code] = entity.NearbyAddress{
+			PostalCode:    code,
+			CommonAddress: locations[0].Prefecture + locations[0].City + extractCommonTown(locations),
+			Lat:           center.Lat,
+			Lon:           center.Lon,
+		}
+		points = append(points, util.IndexedPoint{ID: i, Lat: center.Lat, Lon: center.Lon})
+	}
+
+	return &NearbyService{
+		codes:     codes,
+		addresses: addresses,
+		index:     util.NewGridIndex(nearbyGridCellSize, points),
+	}
+}
+
+// データセットが読み込まれているかを返す
+func (s *NearbyService) Loaded() bool {
+	return s.index.Len() > 0
+}
+
+// 郵便番号の代表座標を返す
+func (s *NearbyService) Locate(postalCode string) (*entity.GeoPoint, bool) {
+	address, ok := s.addresses[postalCode]
+	if !ok {
+		return nil, false
+	}
+	return &entity.GeoPoint{Lat: address.Lat, Lon: address.Lon}, true
+}
+
+// 中心から半径 radiusKm 以内の郵便番号を距離順にページングして返す
+func (s *NearbyService) Search(center entity.GeoPoint, radiusKm float64, limit, offset int) *entity.NearbyResult {
+	neighbors := s.index.Within(center.Lat, center.Lon, radiusKm)
+
+	result := &entity.NearbyResult{
+		Center:   center,
+		RadiusKm: radiusKm,
+		Total:    len(neighbors),
+		Limit:    limit,
+		Offset:   offset,
+		Results:  []entity.NearbyAddress{},
+	}
+	if offset >= len(neighbors) {
+		return result
+	}
+
+	end := offset + limit
+	if end > len(neighbors) {
+		end = len(neighbors)
+	}
+	for _, n := range neighbors[offset:end] {
+		address := s.addresses[s.codes[n.ID]]
+		address.DistanceKm = n.DistanceKm
+		result.Results = append(result.Results, address)
+	}
+	return result
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/util"
+)
+
+// RegionService はローカルデータセットの都道府県・市区町村・町域の階層を保持する
+type RegionService struct {
+	prefectures []entity.Prefecture
+	cities      map[string][]entity.City // 都道府県コードごとの市区町村
+	cityByCode  map[string]entity.City
+	towns       map[string][]entity.Town // 市区町村コードごとの町域
+	postalCodes map[string][]string      // 郵便番号ごとの市区町村コード
+}
+
+// ローカルデータセットから行政区画の階層を構築して RegionService を作成
+func NewRegionService(records []entity.PostalRecord) *RegionService {
+	prefectures := make(map[string]*entity.Prefecture)
+	prefecturePostalCodes := make(map[string]map[string]struct{})
+	cities := make(map[string]*entity.City)
+	cityPostalCodes := make(map[string]map[string]struct{})
+	towns := make(map[string][]entity.Town)
+	seenTowns := make(map[string]map[entity.Town]struct{})
+	postalCityCodes := make(map[string]map[string]struct{})
+
+	for _, r := range records {
+		prefectureCode := r.PrefectureCode()
+		if prefectureCode == "" {
+			// 全国地方公共団体コードのない行は階層に含めない
+			continue
+		}
+
+		if _, ok := prefectures[prefectureCode]; !ok {
+			prefectures[prefectureCode] = &entity.Prefecture{Code: prefectureCode, Name: r.Prefecture, Kana: r.PrefectureKana}
+			prefecturePostalCodes[prefectureCode] = make(map[string]struct{})
+		}
+		prefecturePostalCodes[prefectureCode][r.PostalCode] = struct{}{}
+
+		if _, ok := cities[r.JISCode]; !ok {
+			cities[r.JISCode] = &entity.City{Code: r.JISCode, PrefectureCode: prefectureCode, Name: r.City, Kana: r.CityKana}
+			cityPostalCodes[r.JISCode] = make(map[string]struct{})
+			seenTowns[r.JISCode] = make(map[entity.Town]struct{})
+		}
+		cityPostalCodes[r.JISCode][r.PostalCode] = struct{}{}
+		if _, ok := postalCityCodes[r.PostalCode]; !ok {
+			postalCityCodes[r.PostalCode] = make(map[string]struct{})
+		}
+		postalCityCodes[r.PostalCode][r.JISCode] = struct{}{}
+
+		// 同じ町域が複数行に分かれている場合は 1 件にまとめる
+		town := entity.Town{Name: r.Town, Kana: r.TownKana, PostalCode: r.PostalCode}
+		if _, ok := seenTowns[r.JISCode][town]; !ok {
+			seenTowns[r.JISCode][town] = struct{}{}
+			towns[r.JISCode] = append(towns[r.JISCode], town)
+		}
+	}
+
+	s := &RegionService{
+		cities:      make(map[string][]entity.City),
+		cityByCode:  make(map[string]entity.City, len(cities)),
+		towns:       towns,
+		postalCodes: make(map[string][]string, len(postalCityCodes)),
+	}
+	for postalCode, codes := range postalCityCodes {
+		s.postalCodes[postalCode] = sortedKeys(codes)
+	}
+	for code, p := range prefectures {
+		p.PostalCodeCount = len(prefecturePostalCodes[code])
+		s.prefectures = append(s.prefectures, *p)
+	}
+	sort.Slice(s.prefectures, func(i, j int) bool { return s.prefectures[i].Code < s.prefectures[j].Code })
+
+	for code, c := range cities {
+		c.PostalCodes = sortedKeys(cityPostalCodes[code])
+		s.cities[c.PrefectureCode] = append(s.cities[c.PrefectureCode], *c)
+		s.cityByCode[code] = *c
+	}
+	for _, list := range s.cities {
+		sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	}
+	for _, list := range s.towns {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].PostalCode < list[j].PostalCode })
+	}
+
+	return s
+}
+
+// データセットが読み込まれているかを返す
+func (s *RegionService) Loaded() bool {
+	return len(s.prefectures) > 0
+}
+
+// 都道府県の一覧をコード順に返す
+func (s *RegionService) Prefectures() []entity.Prefecture {
+	return s.prefectures
+}
+
+// 都道府県コードに属する市区町村の一覧を返す
+func (s *RegionService) Cities(prefectureCode string) (*entity.Prefecture, []entity.City, bool) {
+	i := sort.Search(len(s.prefectures), func(i int) bool { return s.prefectures[i].Code >= prefectureCode })
+	if i == len(s.prefectures) || s.prefectures[i].Code != prefectureCode {
+		return nil, nil, false
+	}
+	return &s.prefectures[i], s.cities[prefectureCode], true
+}
+
+// 市区町村コードに属する町域の一覧を返す
+func (s *RegionService) Towns(cityCode string) (*entity.City, []entity.Town, bool) {
+	city, ok := s.cityByCode[cityCode]
+	if !ok {
+		return nil, nil, false
+	}
+	return &city, s.towns[cityCode], true
+}
+
+// 郵便番号に対応する全国地方公共団体コード（5 桁）を返す（複数の市区町村にまたがる場合は false）
+func (s *RegionService) MunicipalityCodeOf(postalCode string) (string, bool) {
+	codes := s.postalCodes[postalCode]
+	if len(codes) != 1 {
+		return "", false
+	}
+	return codes[0], true
+}
+
+// 郵便番号に対応する都道府県コードと全国地方公共団体コード（5 桁）を返す
+// 複数の市区町村にまたがる場合は市区町村コードを空にし、都道府県もまたがる場合は両方とも空にする
+func (s *RegionService) RegionOf(postalCode string) (prefectureCode, cityCode string) {
+	codes := s.postalCodes[postalCode]
+	if len(codes) == 0 {
+		return "", ""
+	}
+	prefectureCode = codes[0][:2]
+	for _, code := range codes[1:] {
+		if code[:2] != prefectureCode {
+			return "", ""
+		}
+	}
+	if len(codes) == 1 {
+		cityCode = codes[0]
+	}
+	return prefectureCode, cityCode
+}
+
+// 全国地方公共団体コード（5 桁）から市区町村を返す
+func (s *RegionService) Municipality(code string) (*entity.Municipality, bool) {
+	city, ok := s.cityByCode[code]
+	if !ok {
+		return nil, false
+	}
+	prefecture, _, _ := s.Cities(city.PrefectureCode)
+	codeWithCheckDigit, err := util.JISCodeWithCheckDigit(code)
+	if err != nil {
+		return nil, false
+	}
+
+	return &entity.Municipality{
+		Code:           codeWithCheckDigit,
+		PrefectureCode: city.PrefectureCode,
+		Prefecture:     prefecture.Name,
+		Name:           city.Name,
+		Kana:           city.Kana,
+		PostalCodes:    city.PostalCodes,
+	}, true
+}
+
+// 集合のキーを昇順のスライスにして返す
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,66 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionService(t *testing.T) {
+	records := []entity.PostalRecord{
+		{PostalCode: "5016121", JISCode: "21201", Prefecture: "岐阜県", PrefectureKana: "ギフケン", City: "岐阜市", CityKana: "ギフシ", Town: "柳津町", TownKana: "ヤナイヅチョウ"},
+		{PostalCode: "5016121", JISCode: "21201", Prefecture: "岐阜県", PrefectureKana: "ギフケン", City: "岐阜市", CityKana: "ギフシ", Town: "柳津町", TownKana: "ヤナイヅチョウ"},
+		{PostalCode: "5010000", JISCode: "21201", Prefecture: "岐阜県", PrefectureKana: "ギフケン", City: "岐阜市", CityKana: "ギフシ", Town: "", TownKana: ""},
+		{PostalCode: "5011100", JISCode: "21213", Prefecture: "岐阜県", PrefectureKana: "ギフケン", City: "各務原市", CityKana: "カカミガハラシ", Town: "", TownKana: ""},
+		{PostalCode: "1000005", JISCode: "13101", Prefecture: "東京都", PrefectureKana: "トウキョウト", City: "千代田区", CityKana: "チヨダク", Town: "丸の内", TownKana: "マルノウチ"},
+		{PostalCode: "0000000", Prefecture: "不明"}, // コードのない行は無視される
+	}
+	s := service.NewRegionService(records)
+	assert.True(t, s.Loaded())
+
+	prefectures := s.Prefectures()
+	assert.Equal(t, []entity.Prefecture{
+		{Code: "13", Name: "東京都", Kana: "トウキョウト", PostalCodeCount: 1},
+		{Code: "21", Name: "岐阜県", Kana: "ギフケン", PostalCodeCount: 3},
+	}, prefectures)
+
+	prefecture, cities, ok := s.Cities("21")
+	assert.True(t, ok)
+	assert.Equal(t, "岐阜県", prefecture.Name)
+	if assert.Len(t, cities, 2) {
+		assert.Equal(t, "21201", cities[0].Code)
+		assert.Equal(t, []string{"5010000", "5016121"}, cities[0].PostalCodes)
+		assert.Equal(t, "各務原市", cities[1].Name)
+	}
+
+	_, towns, ok := s.Towns("21201")
+	assert.True(t, ok)
+	assert.Equal(t, []entity.Town{
+		{Name: "", Kana: "", PostalCode: "5010000"},
+		{Name: "柳津町", Kana: "ヤナイヅチョウ", PostalCode: "5016121"},
+	}, towns)
+
+	code, ok := s.MunicipalityCodeOf("5016121")
+	assert.True(t, ok)
+	assert.Equal(t, "21201", code)
+
+	municipality, ok := s.Municipality("21201")
+	assert.True(t, ok)
+	assert.Equal(t, &entity.Municipality{
+		Code:           "212016",
+		PrefectureCode: "21",
+		Prefecture:     "岐阜県",
+		Name:           "岐阜市",
+		Kana:           "ギフシ",
+		PostalCodes:    []string{"5010000", "5016121"},
+	}, municipality)
+
+	_, _, ok = s.Cities("99")
+	assert.False(t, ok)
+	_, _, ok = s.Towns("99999")
+	assert.False(t, ok)
+	_, ok = s.Municipality("99999")
+	assert.False(t, ok)
+}
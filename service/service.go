@@ -3,4 +3,8 @@ package service
 type ServiceRegistry struct {
 	Address   *AddressService
 	AccessLog *AccessLogService
+	Distance  *DistanceService
+	Nearby    *NearbyService
+	Region    *RegionService
+	Dataset   *DatasetService
 }
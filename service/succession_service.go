@@ -0,0 +1,59 @@
+package service
+
+import "github.com/dkpcb/finatext_kadai_2/entity"
+
+// 後継をたどる最大の回数（循環した対応を打ち切るため）
+const maxSuccessionHops = 10
+
+// SuccessionService は廃止された郵便番号から現在の後継の郵便番号を引く
+type SuccessionService struct {
+	successors map[string]entity.PostalCodeSuccession // 廃止された郵便番号ごとの最新の後継
+	current    map[string]struct{}                    // 現在のデータセットに含まれる郵便番号
+}
+
+// 後継の対応と現在のデータから SuccessionService を作成
+// 現在のデータに含まれる郵便番号（再び使われるようになったもの）は廃止扱いにしない
+func NewSuccessionService(successions []entity.PostalCodeSuccession, records []entity.PostalRecord) *SuccessionService {
+	s := &SuccessionService{
+		successors: make(map[string]entity.PostalCodeSuccession, len(successions)),
+		current:    make(map[string]struct{}),
+	}
+	for _, r := range records {
+		s.current[r.PostalCode] = struct{}{}
+	}
+	for _, succession := range successions {
+		if _, ok := s.current[succession.RetiredPostalCode]; ok {
+			continue
+		}
+		s.successors[succession.RetiredPostalCode] = succession
+	}
+	return s
+}
+
+// 廃止された郵便番号の現在の後継を返す
+// 後継がさらに廃止されている場合は現在のデータに含まれる郵便番号までたどる
+// 返す対応の Version は指定した郵便番号が廃止されたバージョン
+func (s *SuccessionService) Resolve(postalCode string) (entity.PostalCodeSuccession, bool) {
+	first, ok := s.successors[postalCode]
+	if !ok {
+		return entity.PostalCodeSuccession{}, false
+	}
+
+	successor := first.SuccessorPostalCode
+	for i := 0; i < maxSuccessionHops; i++ {
+		if _, ok := s.current[successor]; ok {
+			break
+		}
+		next, ok := s.successors[successor]
+		if !ok {
+			break
+		}
+		successor = next.SuccessorPostalCode
+	}
+	if successor == postalCode {
+		return entity.PostalCodeSuccession{}, false
+	}
+
+	first.SuccessorPostalCode = successor
+	return first, true
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+)
+
+// 集計できる期間を超えた場合のエラー
+var ErrWindowOutOfRange = errors.New("trending window out of range")
+
+// TrendingCounter は郵便番号ごとのアクセス数を一定間隔の区間の輪（リングバッファ）で数え、直近の期間の上位を返す
+// 区間ごとに最大 capacity 件の郵便番号を Space-Saving 法で数えるため、件数は多めに見積もられることがある
+type TrendingCounter struct {
+	resolution time.Duration
+	maxWindow  time.Duration
+	capacity   int
+
+	mu      sync.Mutex
+	buckets []trendingBucket
+}
+
+// 1 区間のアクセス数
+type trendingBucket struct {
+	slot   int64 // 区間の通し番号（時刻 / resolution）
+	counts map[string]int
+}
+
+// 区間の長さ、集計できる最大の期間、区間ごとに数える郵便番号の数を指定して TrendingCounter を作成
+func NewTrendingCounter(resolution, maxWindow time.Duration, capacity int) *TrendingCounter {
+	if resolution <= 0 {
+		resolution = time.Minute
+	}
+	if maxWindow < resolution {
+		maxWindow = resolution
+	}
+	if capacity <= 0 {
+		capacity = 1
+	}
+	// 直前の期間と比べるため、最大の期間の 2 倍の区間を保持する
+	n := 2 * int((maxWindow+resolution-1)/resolution)
+	return &TrendingCounter{
+		resolution: resolution,
+		maxWindow:  maxWindow,
+		capacity:   capacity,
+		buckets:    make([]trendingBucket, n),
+	}
+}
+
+// 集計できる最大の期間を返す
+func (c *TrendingCounter) MaxWindow() time.Duration {
+	return c.maxWindow
+}
+
+// 時刻 t のアクセスを数える
+func (c *TrendingCounter) Record(postalCode string, t time.Time) {
+	slot := c.slot(t)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.bucket(slot)
+	if b == nil {
+		// 保持している区間より古いアクセスは数えない
+		return
+	}
+	if _, ok := b.counts[postalCode]; ok || len(b.counts) < c.capacity {
+		b.counts[postalCode]++
+		return
+	}
+
+	// 満杯の場合は最も少ない郵便番号を置き換え、その件数を引き継ぐ（Space-Saving）
+	minCode, minCount := "", 0
+	for code, count := range b.counts {
+		if minCode == "" || count < minCount || (count == minCount && code < minCode) {
+			minCode, minCount = code, count
+		}
+	}
+	delete(b.counts, minCode)
+	b.counts[postalCode] = minCount + 1
+}
+
+// now までの window の期間にアクセスの多い郵便番号を最大 limit 件、直前の同じ長さの期間との比較とともに返す
+// window は区間の長さに切り上げる
+func (c *TrendingCounter) Top(window time.Duration, limit int, now time.Time) (*entity.TrendingList, error) {
+	if window <= 0 || window > c.maxWindow {
+		return nil, ErrWindowOutOfRange
+	}
+	m := int64((window + c.resolution - 1) / c.resolution)
+	end := c.slot(now)
+
+	c.mu.Lock()
+	current := c.sum(end-m+1, end)
+	previous := c.sum(end-2*m+1, end-m)
+	c.mu.Unlock()
+
+	postalCodes := make([]entity.TrendingPostalCode, 0, len(current))
+	for code, count := range current {
+		t := entity.TrendingPostalCode{PostalCode: code, RequestCount: count, PreviousCount: previous[code]}
+		if t.PreviousCount > 0 {
+			growth := percentage(t.RequestCount-t.PreviousCount, t.PreviousCount)
+			t.Growth = &growth
+		}
+		postalCodes = append(postalCodes, t)
+	}
+	sort.Slice(postalCodes, func(i, j int) bool {
+		if postalCodes[i].RequestCount != postalCodes[j].RequestCount {
+			return postalCodes[i].RequestCount > postalCodes[j].RequestCount
+		}
+		return postalCodes[i].PostalCode < postalCodes[j].PostalCode
+	})
+	if limit > 0 && len(postalCodes) > limit {
+		postalCodes = postalCodes[:limit]
+	}
+
+	return &entity.TrendingList{
+		Window:      (time.Duration(m) * c.resolution).String(),
+		From:        time.Unix(0, (end-m+1)*int64(c.resolution)).In(now.Location()),
+		To:          time.Unix(0, (end+1)*int64(c.resolution)).In(now.Location()),
+		PostalCodes: postalCodes,
+	}, nil
+}
+
+// 時刻の区間の通し番号
+func (c *TrendingCounter) slot(t time.Time) int64 {
+	return t.UnixNano() / int64(c.resolution)
+}
+
+// 区間を返す（古い区間が残っていれば空にする。保持している範囲より古い場合は nil）
+// mu を保持して呼び出す
+func (c *TrendingCounter) bucket(slot int64) *trendingBucket {
+	b := &c.buckets[int(slot%int64(len(c.buckets)))]
+	if b.slot > slot {
+		return nil
+	}
+	if b.slot < slot || b.counts == nil {
+		b.slot = slot
+		b.counts = make(map[string]int)
+	}
+	return b
+}
+
+// from から to までの区間のアクセス数を郵便番号ごとに合計する（mu を保持して呼び出す）
+func (c *TrendingCounter) sum(from, to int64) map[string]int {
+	total := make(map[string]int)
+	for slot := from; slot <= to; slot++ {
+		b := c.buckets[int(slot%int64(len(c.buckets)))]
+		if b.slot != slot {
+			continue
+		}
+		for code, count := range b.counts {
+			total[code] += count
+		}
+	}
+	return total
+}
@@ -0,0 +1,93 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dkpcb/finatext_kadai_2/entity"
+	"github.com/dkpcb/finatext_kadai_2/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrendingCounterTop(t *testing.T) {
+	c := service.NewTrendingCounter(time.Minute, time.Hour, 100)
+	now := time.Date(2024, 1, 1, 12, 30, 30, 0, time.UTC)
+
+	// 直前の 15 分
+	for i := 0; i < 2; i++ {
+		c.Record("1000001", now.Add(-20*time.Minute))
+	}
+	c.Record("5016121", now.Add(-20*time.Minute))
+	// 直近の 15 分
+	for i := 0; i < 3; i++ {
+		c.Record("1000001", now.Add(-time.Minute))
+	}
+	for i := 0; i < 5; i++ {
+		c.Record("0600000", now)
+	}
+	// 期間外
+	c.Record("9999999", now.Add(-40*time.Minute))
+
+	list, err := c.Top(15*time.Minute, 10, now)
+	require.NoError(t, err)
+
+	growth := 50.0
+	assert.Equal(t, "15m0s", list.Window)
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 16, 0, 0, time.UTC), list.From)
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 31, 0, 0, time.UTC), list.To)
+	assert.Equal(t, []entity.TrendingPostalCode{
+		{PostalCode: "0600000", RequestCount: 5},
+		{PostalCode: "1000001", RequestCount: 3, PreviousCount: 2, Growth: &growth},
+	}, list.PostalCodes)
+
+	list, err = c.Top(15*time.Minute, 1, now)
+	require.NoError(t, err)
+	assert.Len(t, list.PostalCodes, 1)
+}
+
+func TestTrendingCounterExpiresOldBuckets(t *testing.T) {
+	c := service.NewTrendingCounter(time.Minute, 10*time.Minute, 100)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	c.Record("1000001", start)
+	// 輪を一周した後の同じ位置の区間
+	c.Record("5016121", start.Add(20*time.Minute))
+
+	list, err := c.Top(10*time.Minute, 10, start.Add(20*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []entity.TrendingPostalCode{{PostalCode: "5016121", RequestCount: 1}}, list.PostalCodes)
+
+	// 保持している範囲より古いアクセスは数えない
+	c.Record("1000001", start)
+	list, err = c.Top(10*time.Minute, 10, start.Add(20*time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, list.PostalCodes, 1)
+}
+
+func TestTrendingCounterSpaceSaving(t *testing.T) {
+	c := service.NewTrendingCounter(time.Minute, time.Hour, 2)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		c.Record("1000001", now)
+	}
+	c.Record("5016121", now)
+	c.Record("0600000", now) // 最も少ない 5016121 を置き換えて 2 件と見積もる
+
+	list, err := c.Top(time.Minute, 10, now)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.TrendingPostalCode{
+		{PostalCode: "1000001", RequestCount: 5},
+		{PostalCode: "0600000", RequestCount: 2},
+	}, list.PostalCodes)
+}
+
+func TestTrendingCounterWindowOutOfRange(t *testing.T) {
+	c := service.NewTrendingCounter(time.Minute, time.Hour, 10)
+
+	_, err := c.Top(2*time.Hour, 10, time.Now())
+	assert.ErrorIs(t, err, service.ErrWindowOutOfRange)
+	_, err = c.Top(0, 10, time.Now())
+	assert.ErrorIs(t, err, service.ErrWindowOutOfRange)
+}
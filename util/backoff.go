@@ -0,0 +1,25 @@
+package util
+
+import (
+	"math"
+	"time"
+)
+
+// attempt 回目（0 始まり）の失敗の後に待つ時間を返す
+// initial から倍々に増やし、max で頭打ちにする（max が 0 以下の場合は上限なし）
+func ExponentialBackoff(attempt int, initial, max time.Duration) time.Duration {
+	if max <= 0 {
+		max = math.MaxInt64
+	}
+	delay := initial
+	for i := 0; i < attempt; i++ {
+		if delay > max/2 {
+			return max
+		}
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{4, 16 * time.Second},
+		{5, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := ExponentialBackoff(tt.attempt, time.Second, 30*time.Second); got != tt.want {
+			t.Errorf("ExponentialBackoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestExponentialBackoffWithoutMax(t *testing.T) {
+	if got := ExponentialBackoff(3, time.Second, 0); got != 8*time.Second {
+		t.Errorf("ExponentialBackoff(3) = %v, want 8s", got)
+	}
+	// 桁あふれせずに最大値で頭打ちにする
+	if got := ExponentialBackoff(100, time.Second, 0); got <= 0 {
+		t.Errorf("ExponentialBackoff(100) = %v, want positive", got)
+	}
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"math"
+	"sort"
+)
+
+// 緯度 1 度あたりの距離 [km]
+const kmPerDegree = EarthRadius * math.Pi / 180.0
+
+// IndexedPoint は空間インデックスに登録する地点
+type IndexedPoint struct {
+	ID  int
+	Lat float64
+	Lon float64
+}
+
+// Neighbor は検索結果の地点と中心からの距離
+type Neighbor struct {
+	ID         int
+	DistanceKm float64
+}
+
+type gridCell struct {
+	x, y int
+}
+
+// GridIndex は緯度経度を一定間隔のグリッドに分割した空間インデックス
+type GridIndex struct {
+	cellSize float64
+	cells    map[gridCell][]IndexedPoint
+	size     int
+}
+
+// 新しい GridIndex を作成（cellSize はグリッドの一辺 [度]）
+func NewGridIndex(cellSize float64, points []IndexedPoint) *GridIndex {
+	if cellSize <= 0 {
+		cellSize = 0.1
+	}
+	idx := &GridIndex{cellSize: cellSize, cells: make(map[gridCell][]IndexedPoint)}
+	for _, p := range points {
+		cell := idx.cellOf(p.Lat, p.Lon)
+		idx.cells[cell] = append(idx.cells[cell], p)
+	}
+	idx.size = len(points)
+	return idx
+}
+
+// 登録されている地点数を返す
+func (idx *GridIndex) Len() int {
+	return idx.size
+}
+
+// 中心から半径 radiusKm 以内の地点を距離の昇順で返す
+func (idx *GridIndex) Within(lat, lon, radiusKm float64) []Neighbor {
+	if radiusKm < 0 {
+		return nil
+	}
+
+	// 半径を覆う緯度経度の範囲を求め、該当するセルだけを走査
+	dLat := radiusKm / kmPerDegree
+	cosLat := math.Cos(lat * math.Pi / 180.0)
+	dLon := 180.0
+	if cosLat > 1e-6 {
+		dLon = math.Min(dLon, radiusKm/(kmPerDegree*cosLat))
+	}
+	minCell := idx.cellOf(lat-dLat, lon-dLon)
+	maxCell := idx.cellOf(lat+dLat, lon+dLon)
+
+	var neighbors []Neighbor
+	for x := minCell.x; x <= maxCell.x; x++ {
+		for y := minCell.y; y <= maxCell.y; y++ {
+			for _, p := range idx.cells[gridCell{x: x, y: y}] {
+				distance := CalculateDistance(lat, lon, p.Lat, p.Lon)
+				if distance <= radiusKm {
+					neighbors = append(neighbors, Neighbor{ID: p.ID, DistanceKm: distance})
+				}
+			}
+		}
+	}
+
+	sort.Slice(neighbors, func(i, j int) bool {
+		if neighbors[i].DistanceKm != neighbors[j].DistanceKm {
+			return neighbors[i].DistanceKm < neighbors[j].DistanceKm
+		}
+		return neighbors[i].ID < neighbors[j].ID
+	})
+	return neighbors
+}
+
+func (idx *GridIndex) cellOf(lat, lon float64) gridCell {
+	return gridCell{
+		x: int(math.Floor(lon / idx.cellSize)),
+		y: int(math.Floor(lat / idx.cellSize)),
+	}
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestGridIndex_Within(t *testing.T) {
+	points := []IndexedPoint{
+		{ID: 0, Lat: TokyoStationLat, Lon: TokyoStationLon}, // 東京駅
+		{ID: 1, Lat: 35.658034, Lon: 139.701636},            // 渋谷駅
+		{ID: 2, Lat: 35.443707, Lon: 139.638031},            // 横浜駅
+		{ID: 3, Lat: 34.702485, Lon: 135.495951},            // 大阪駅
+	}
+	idx := NewGridIndex(0.05, points)
+
+	tests := []struct {
+		name     string
+		radiusKm float64
+		wantIDs  []int
+	}{
+		{"半径 1km（自分自身のみ）", 1, []int{0}},
+		{"半径 10km", 10, []int{0, 1}},
+		{"半径 30km", 30, []int{0, 1, 2}},
+		{"半径 500km", 500, []int{0, 1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := idx.Within(TokyoStationLat, TokyoStationLon, tt.radiusKm)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("Within() returned %d points, want %d: %+v", len(got), len(tt.wantIDs), got)
+			}
+			for i, n := range got {
+				if n.ID != tt.wantIDs[i] {
+					t.Errorf("Within()[%d].ID = %d, want %d", i, n.ID, tt.wantIDs[i])
+				}
+				if n.DistanceKm > tt.radiusKm {
+					t.Errorf("Within()[%d].DistanceKm = %v exceeds radius %v", i, n.DistanceKm, tt.radiusKm)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestHyperLogLogCount(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 100000} {
+		h := NewHyperLogLog()
+		for i := 0; i < n; i++ {
+			h.Add(fmt.Sprintf("client-%d", i))
+			// 同じ要素を何度追加しても数は変わらない
+			h.Add(fmt.Sprintf("client-%d", i))
+		}
+
+		got := h.Count()
+		if math.Abs(float64(got-n)) > math.Max(1, float64(n)*0.1) {
+			t.Errorf("Count() with %d clients = %d", n, got)
+		}
+	}
+}
+
+func TestHyperLogLogMergeAndBytes(t *testing.T) {
+	a, b := NewHyperLogLog(), NewHyperLogLog()
+	for i := 0; i < 500; i++ {
+		a.Add(fmt.Sprintf("client-%d", i))
+		b.Add(fmt.Sprintf("client-%d", i+250))
+	}
+
+	restored, err := HyperLogLogFromBytes(a.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored.Merge(b)
+
+	if got := restored.Count(); math.Abs(float64(got-750)) > 75 {
+		t.Errorf("merged Count() = %d, want about 750", got)
+	}
+	if _, err := HyperLogLogFromBytes([]byte{1, 2, 3}); err == nil {
+		t.Error("HyperLogLogFromBytes accepted an invalid sketch")
+	}
+}
+
+func TestClientID(t *testing.T) {
+	id := ClientID([]byte("salt"), "192.0.2.1")
+
+	if len(id) != 32 {
+		t.Errorf("ClientID length = %d", len(id))
+	}
+	if id != ClientID([]byte("salt"), "192.0.2.1") {
+		t.Error("ClientID is not deterministic")
+	}
+	if id == ClientID([]byte("other"), "192.0.2.1") {
+		t.Error("ClientID does not depend on the salt")
+	}
+}
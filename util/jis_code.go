@@ -0,0 +1,56 @@
+package util
+
+import (
+	"errors"
+)
+
+// ErrInvalidJISCode は全国地方公共団体コードの形式が不正な場合のエラー
+var ErrInvalidJISCode = errors.New("invalid local government code")
+
+// 検査数字の計算に使う各桁の重み
+var jisCodeWeights = [5]int{6, 5, 4, 3, 2}
+
+// 5 桁の全国地方公共団体コードの検査数字を計算
+func JISCheckDigit(code string) (byte, error) {
+	if len(code) != 5 {
+		return 0, ErrInvalidJISCode
+	}
+
+	sum := 0
+	for i := 0; i < 5; i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return 0, ErrInvalidJISCode
+		}
+		sum += int(code[i]-'0') * jisCodeWeights[i]
+	}
+
+	// 11 から余りを引いた値の下 1 桁（余りが 0 なら 1、1 なら 0）
+	return byte('0' + (11-sum%11)%10), nil
+}
+
+// 5 桁のコードに検査数字を付けた 6 桁のコードを返す
+func JISCodeWithCheckDigit(code string) (string, error) {
+	digit, err := JISCheckDigit(code)
+	if err != nil {
+		return "", err
+	}
+	return code + string(digit), nil
+}
+
+// 5 桁または検査数字付き 6 桁のコードを検証して 5 桁のコードを返す
+func NormalizeJISCode(code string) (string, error) {
+	switch len(code) {
+	case 5:
+		if _, err := JISCheckDigit(code); err != nil {
+			return "", err
+		}
+		return code, nil
+	case 6:
+		digit, err := JISCheckDigit(code[:5])
+		if err != nil || digit != code[5] {
+			return "", ErrInvalidJISCode
+		}
+		return code[:5], nil
+	}
+	return "", ErrInvalidJISCode
+}
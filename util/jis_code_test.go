@@ -0,0 +1,57 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestJISCodeWithCheckDigit(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"岐阜県岐阜市", "21201", "212016"},
+		{"東京都千代田区", "13101", "131016"},
+		{"北海道札幌市", "01100", "011002"},
+		{"栃木県（余りが 1）", "09000", "090000"},
+		{"静岡県（余りが 0）", "22000", "220001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JISCodeWithCheckDigit(tt.code)
+			if err != nil {
+				t.Fatalf("JISCodeWithCheckDigit() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("JISCodeWithCheckDigit() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeJISCode(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{"21201", "21201", false},
+		{"212016", "21201", false},
+		{"212017", "", true}, // 検査数字が不一致
+		{"2120", "", true},
+		{"2120a", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := NormalizeJISCode(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeJISCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeJISCode() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Accept や Accept-Language のような q 値付きのリストを、q 値の降順の値に分解する
+// 値は小文字にし、q 値が同じ場合は書かれた順を保つ。q=0 の値と空の値は除外する
+func ParseQualityList(header string) []string {
+	type item struct {
+		value string
+		q     float64
+	}
+
+	var items []item
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		it := item{value: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		for _, param := range params[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					it.q = q
+				}
+			}
+		}
+		if it.value != "" && it.q > 0 {
+			items = append(items, it)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+
+	values := make([]string, len(items))
+	for i, it := range items {
+		values[i] = it.value
+	}
+	return values
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQualityList(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []string
+	}{
+		{"空", "", []string{}},
+		{"q 値なしは 1", "text/csv, application/json", []string{"text/csv", "application/json"}},
+		{"q 値の降順", "application/json;q=0.5, text/xml;q=0.9, text/csv", []string{"text/csv", "text/xml", "application/json"}},
+		{"q=0 は除外", "ja;q=0, en", []string{"en"}},
+		{"q 以外のパラメータは無視", "application/vnd.msgpack; charset=binary; q=0.8, */*;q=0.1", []string{"application/vnd.msgpack", "*/*"}},
+		{"小文字にする", "ja-JP, EN-us;q=0.5", []string{"ja-jp", "en-us"}},
+		{"不正な q 値は 1", "ja;q=high, en;q=0.9", []string{"ja", "en"}},
+		{"空の値は除外", " , ja", []string{"ja"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseQualityList(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQualityList(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
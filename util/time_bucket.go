@@ -0,0 +1,38 @@
+package util
+
+import "time"
+
+// 時系列の集計単位
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+	IntervalWeek = "week" // 月曜始まり
+)
+
+// 指定したタイムゾーンで、日時を含む集計単位の開始日時を返す
+func TruncateToInterval(t time.Time, interval string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch interval {
+	case IntervalDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	case IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		// 月曜日からの日数
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	}
+}
+
+// 次の集計単位の開始日時を返す（日・週は夏時間の切り替えがあっても暦の上で進める）
+func AddInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalDay:
+		return t.AddDate(0, 0, 1)
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.Add(time.Hour)
+	}
+}
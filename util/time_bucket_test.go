@@ -0,0 +1,50 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTruncateToInterval(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	// UTC の 2024-01-07 (日) 16:30 は JST の 2024-01-08 (月) 01:30
+	at := time.Date(2024, 1, 7, 16, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		interval string
+		want     time.Time
+	}{
+		{IntervalHour, time.Date(2024, 1, 8, 1, 0, 0, 0, jst)},
+		{IntervalDay, time.Date(2024, 1, 8, 0, 0, 0, 0, jst)},
+		{IntervalWeek, time.Date(2024, 1, 8, 0, 0, 0, 0, jst)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.interval, func(t *testing.T) {
+			got := TruncateToInterval(at, tt.interval, jst)
+			if !got.Equal(tt.want) {
+				t.Errorf("TruncateToInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 週の途中（日曜日）は前の月曜日に切り捨てる
+	sunday := time.Date(2024, 1, 14, 23, 59, 0, 0, jst)
+	if got, want := TruncateToInterval(sunday, IntervalWeek, jst), time.Date(2024, 1, 8, 0, 0, 0, 0, jst); !got.Equal(want) {
+		t.Errorf("TruncateToInterval(week) = %v, want %v", got, want)
+	}
+}
+
+func TestAddInterval(t *testing.T) {
+	start := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	if got := AddInterval(start, IntervalHour); !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("AddInterval(hour) = %v", got)
+	}
+	if got := AddInterval(start, IntervalDay); !got.Equal(time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("AddInterval(day) = %v", got)
+	}
+	if got := AddInterval(start, IntervalWeek); !got.Equal(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("AddInterval(week) = %v", got)
+	}
+}